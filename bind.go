@@ -0,0 +1,192 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// defaultMultipartMemory 解析 multipart/form-data 的时候，最多放在内存里面的字节数
+const defaultMultipartMemory = 32 << 20
+
+// ServerWithBodyLimit 设置绑定请求体时候的大小限制，0 代表不限制
+// 路由上通过 RouteWithBodyLimit 设置的限制优先
+func ServerWithBodyLimit(limit int64) HTTPServerOption {
+	return func(server *HTTPServer) {
+		server.bodyLimit = limit
+	}
+}
+
+// ServerWithStrictBinding 使用严格模式绑定请求
+// JSON 里面有结构体没有的字段，或者表单的请求体里面有结构体没有的字段的时候，返回 400。
+// 查询参数不受严格模式影响
+func ServerWithStrictBinding() HTTPServerOption {
+	return func(server *HTTPServer) {
+		server.strictBinding = true
+	}
+}
+
+// Bind 根据 Content-Type 选择绑定的方式
+// 表单之外的 Content-Type 使用对应的 Codec 绑定，默认支持 JSON 和 XML，参考 ServerWithCodec。
+// 没有对应 Codec 的 Content-Type 返回 415。
+// 没有 Content-Type 的时候按照表单处理，也就是只会绑定查询参数。
+// 返回的 error 都是 *HTTPError，可以直接交给 ErrorHandler 处理
+func (c *Context) Bind(val any) error {
+	ct := c.Req.Header.Get("Content-Type")
+	if ct == "" {
+		return c.BindForm(val)
+	}
+	mediaType, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return &HTTPError{Code: http.StatusUnsupportedMediaType, Message: "非法的 Content-Type " + ct, Err: err}
+	}
+	if mediaType == "application/x-www-form-urlencoded" || mediaType == "multipart/form-data" {
+		return c.BindForm(val)
+	}
+	return c.BindWith(mediaType, val)
+}
+
+// BindJSON 把请求体当作 JSON 绑定到 val 上
+// 严格模式下，不允许出现 val 没有的字段
+func (c *Context) BindJSON(val any) error {
+	return c.BindWith("application/json", val)
+}
+
+// BindXML 把请求体当作 XML 绑定到 val 上
+func (c *Context) BindXML(val any) error {
+	return c.BindWith("application/xml", val)
+}
+
+// BindWith 不管 Content-Type，使用 mediaType 对应的 Codec 把请求体绑定到 val 上
+// 没有对应的 Codec 的时候返回 415
+func (c *Context) BindWith(mediaType string, val any) error {
+	if val == nil {
+		return errors.New("web: 输入不能为 nil")
+	}
+	codec, ok := c.codec(mediaType)
+	if !ok {
+		return &HTTPError{Code: http.StatusUnsupportedMediaType, Message: "不支持的 Content-Type " + mediaType}
+	}
+	if !c.hasBody() {
+		return c.bindError(" "+mediaType+" ", io.EOF)
+	}
+	if err := codec.Decode(c.body(), val, c.strictBinding); err != nil {
+		return c.bindError(" "+mediaType+" ", err)
+	}
+	return nil
+}
+
+// BindForm 把表单绑定到 val 上，val 必须是指向结构体的指针
+// 表单包括查询参数，application/x-www-form-urlencoded 和 multipart/form-data 格式的请求体。
+// 字段名由 form 标签决定，没有标签的时候使用字段名，form:"-" 代表忽略这个字段。
+// 支持基本类型，time.Duration，实现了 encoding.TextUnmarshaler 的类型，以及它们的指针和切片。
+// 表单里面没有的字段，可以用 default 标签指定默认值，切片用逗号分隔多个默认值，例如 default:"a,b"。
+// 严格模式下，不允许请求体里面出现 val 没有的字段，查询参数不受影响
+func (c *Context) BindForm(val any) error {
+	ct := c.Req.Header.Get("Content-Type")
+	multipart := strings.HasPrefix(ct, "multipart/form-data")
+	if !c.hasBody() {
+		// 声明了表单类型，却没有请求体
+		if multipart || strings.HasPrefix(ct, "application/x-www-form-urlencoded") {
+			return c.bindError("表单", io.EOF)
+		}
+		// 只绑定查询参数，ParseForm 遇到 nil 的请求体会报错
+		c.Req.Body = http.NoBody
+	}
+	c.Req.Body = c.body()
+	var err error
+	if multipart {
+		err = c.Req.ParseMultipartForm(defaultMultipartMemory)
+	} else {
+		err = c.Req.ParseForm()
+	}
+	if err != nil {
+		return c.bindError("表单", err)
+	}
+	// 严格模式只检查请求体，查询参数里面经常有 utm_source 之类和业务无关的参数
+	// ParseMultipartForm 也会把 multipart/form-data 里面的值放进 PostForm
+	var strictValues url.Values
+	if c.strictBinding {
+		strictValues = c.Req.PostForm
+		if strictValues == nil {
+			strictValues = url.Values{}
+		}
+	}
+	if err = bindValues(val, "form", c.Req.Form, nil, strictValues); err != nil {
+		return c.bindError("表单", err)
+	}
+	return nil
+}
+
+// BindQuery 把查询参数绑定到 val 上，val 必须是指向结构体的指针
+// 字段名由 query 标签决定，支持的类型和 default 标签参考 BindForm
+func (c *Context) BindQuery(val any) error {
+	if err := bindValues(val, "query", c.queryValues(), nil, nil); err != nil {
+		return c.bindError("查询参数", err)
+	}
+	return nil
+}
+
+// BindHeader 把请求头绑定到 val 上，val 必须是指向结构体的指针
+// 字段名由 header 标签决定，大小写不敏感。支持的类型和 default 标签参考 BindForm
+func (c *Context) BindHeader(val any) error {
+	if err := bindValues(val, "header", c.Req.Header, http.CanonicalHeaderKey, nil); err != nil {
+		return c.bindError("请求头", err)
+	}
+	return nil
+}
+
+// BindPath 把路径参数绑定到 val 上，val 必须是指向结构体的指针
+// 字段名由 path 标签决定，支持的类型和 default 标签参考 BindForm
+func (c *Context) BindPath(val any) error {
+	values := make(map[string][]string, len(c.PathParams))
+	for k, v := range c.PathParams {
+		values[k] = []string{v}
+	}
+	if err := bindValues(val, "path", values, nil, nil); err != nil {
+		return c.bindError("路径参数", err)
+	}
+	return nil
+}
+
+// hasBody 判断请求是否带有请求体，nil 和 http.NoBody 都代表没有请求体
+func (c *Context) hasBody() bool {
+	return c.Req.Body != nil && c.Req.Body != http.NoBody
+}
+
+// body 返回带有大小限制的请求体
+func (c *Context) body() io.ReadCloser {
+	limit := c.bodyLimit
+	if c.RouteMeta != nil && c.RouteMeta.BodyLimit > 0 {
+		limit = c.RouteMeta.BodyLimit
+	}
+	if limit <= 0 || c.Req.Body == nil {
+		return c.Req.Body
+	}
+	return http.MaxBytesReader(c.Resp, c.Req.Body, limit)
+}
+
+// bindError 把绑定时候的 error 转换成 *HTTPError
+// 请求体太大返回 413，其它的返回 400
+func (c *Context) bindError(format string, err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return &HTTPError{
+			Code:    http.StatusRequestEntityTooLarge,
+			Message: fmt.Sprintf("请求体超过了 %d 字节", maxBytesErr.Limit),
+			Err:     err,
+		}
+	}
+	if errors.Is(err, io.EOF) {
+		return &HTTPError{Code: http.StatusBadRequest, Message: "请求体为空", Err: err}
+	}
+	var bindErr *BindError
+	if errors.As(err, &bindErr) {
+		return &HTTPError{Code: http.StatusBadRequest, Message: bindErr.Error(), Err: err}
+	}
+	return &HTTPError{Code: http.StatusBadRequest, Message: "非法的" + format + "请求", Err: err}
+}
@@ -0,0 +1,363 @@
+package web
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bindUser struct {
+	Name  string   `json:"name" xml:"name" form:"name"`
+	Age   int      `json:"age" xml:"age" form:"age"`
+	Tags  []string `json:"tags" xml:"tag" form:"tag"`
+	Email *string  `json:"email" xml:"email" form:"email"`
+}
+
+func TestContext_Bind(t *testing.T) {
+	email := "tom@example.com"
+	testCases := []struct {
+		name        string
+		opts        []HTTPServerOption
+		routeOpts   []RouteOption
+		contentType string
+		target      string
+		body        string
+		wantUser    bindUser
+		wantCode    int
+	}{
+		{
+			name:        "json",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"name":"Tom","age":18,"tags":["a","b"],"email":"tom@example.com","extra":1}`,
+			wantUser:    bindUser{Name: "Tom", Age: 18, Tags: []string{"a", "b"}, Email: &email},
+		},
+		{
+			name:        "problem json",
+			contentType: "application/merge-patch+json",
+			body:        `{"name":"Tom"}`,
+			wantUser:    bindUser{Name: "Tom"},
+		},
+		{
+			name:        "json strict",
+			opts:        []HTTPServerOption{ServerWithStrictBinding()},
+			contentType: "application/json",
+			body:        `{"name":"Tom","extra":1}`,
+			wantCode:    http.StatusBadRequest,
+		},
+		{
+			name:        "json syntax",
+			contentType: "application/json",
+			body:        `{"name":`,
+			wantCode:    http.StatusBadRequest,
+		},
+		{
+			name:        "json empty",
+			contentType: "application/json",
+			wantCode:    http.StatusBadRequest,
+		},
+		{
+			name:        "json too large",
+			opts:        []HTTPServerOption{ServerWithBodyLimit(8)},
+			contentType: "application/json",
+			body:        `{"name":"Tom"}`,
+			wantCode:    http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:        "route body limit",
+			opts:        []HTTPServerOption{ServerWithBodyLimit(1024)},
+			routeOpts:   []RouteOption{RouteWithBodyLimit(8)},
+			contentType: "application/json",
+			body:        `{"name":"Tom"}`,
+			wantCode:    http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:        "xml",
+			contentType: "application/xml",
+			body:        `<user><name>Tom</name><age>18</age><tag>a</tag><tag>b</tag></user>`,
+			wantUser:    bindUser{Name: "Tom", Age: 18, Tags: []string{"a", "b"}},
+		},
+		{
+			name:        "form",
+			contentType: "application/x-www-form-urlencoded",
+			target:      "/user?tag=c",
+			body:        "name=Tom&age=18&tag=a&tag=b&email=tom%40example.com",
+			wantUser:    bindUser{Name: "Tom", Age: 18, Tags: []string{"a", "b", "c"}, Email: &email},
+		},
+		{
+			name:        "form invalid int",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "age=abc",
+			wantCode:    http.StatusBadRequest,
+		},
+		{
+			name:        "form strict",
+			opts:        []HTTPServerOption{ServerWithStrictBinding()},
+			contentType: "application/x-www-form-urlencoded",
+			body:        "name=Tom&extra=1",
+			wantCode:    http.StatusBadRequest,
+		},
+		{
+			// 严格模式不检查查询参数
+			name:        "form strict with query",
+			opts:        []HTTPServerOption{ServerWithStrictBinding()},
+			contentType: "application/x-www-form-urlencoded",
+			target:      "/user?utm_source=x",
+			body:        "name=Tom",
+			wantUser:    bindUser{Name: "Tom"},
+		},
+		{
+			name:     "query only",
+			target:   "/user?name=Tom&age=18",
+			wantUser: bindUser{Name: "Tom", Age: 18},
+		},
+		{
+			name:        "unsupported",
+			contentType: "text/plain",
+			body:        "Tom",
+			wantCode:    http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "invalid content type",
+			contentType: "application/",
+			wantCode:    http.StatusUnsupportedMediaType,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewHTTPServer(tc.opts...)
+			var u bindUser
+			s.PostErr("/user", func(ctx *Context) error {
+				return ctx.Bind(&u)
+			}, tc.routeOpts...)
+			target := tc.target
+			if target == "" {
+				target = "/user"
+			}
+			req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, req)
+			if tc.wantCode == 0 {
+				tc.wantCode = http.StatusOK
+			}
+			assert.Equal(t, tc.wantCode, recorder.Code, recorder.Body.String())
+			if tc.wantCode == http.StatusOK {
+				assert.Equal(t, tc.wantUser, u)
+			}
+		})
+	}
+}
+
+func TestContext_Bind_noBody(t *testing.T) {
+	testCases := []struct {
+		name        string
+		body        io.ReadCloser
+		contentType string
+		bind        func(ctx *Context, u *bindUser) error
+		wantErr     error
+		wantUser    bindUser
+	}{
+		{
+			name:    "json nil",
+			bind:    func(ctx *Context, u *bindUser) error { return ctx.BindJSON(u) },
+			wantErr: &HTTPError{Code: http.StatusBadRequest, Message: "请求体为空", Err: io.EOF},
+		},
+		{
+			name:    "xml no body",
+			body:    http.NoBody,
+			bind:    func(ctx *Context, u *bindUser) error { return ctx.BindXML(u) },
+			wantErr: &HTTPError{Code: http.StatusBadRequest, Message: "请求体为空", Err: io.EOF},
+		},
+		{
+			name:        "form nil",
+			contentType: "application/x-www-form-urlencoded",
+			bind:        func(ctx *Context, u *bindUser) error { return ctx.BindForm(u) },
+			wantErr:     &HTTPError{Code: http.StatusBadRequest, Message: "请求体为空", Err: io.EOF},
+		},
+		{
+			name:        "multipart no body",
+			body:        http.NoBody,
+			contentType: "multipart/form-data; boundary=abc",
+			bind:        func(ctx *Context, u *bindUser) error { return ctx.BindForm(u) },
+			wantErr:     &HTTPError{Code: http.StatusBadRequest, Message: "请求体为空", Err: io.EOF},
+		},
+		{
+			name:     "query only",
+			bind:     func(ctx *Context, u *bindUser) error { return ctx.Bind(u) },
+			wantUser: bindUser{Name: "Tom"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "/user?name=Tom", nil)
+			require.NoError(t, err)
+			req.Body = tc.body
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			s := NewHTTPServer()
+			ctx := &Context{Req: req, Resp: httptest.NewRecorder(), codecs: s.codecs}
+			var u bindUser
+			err = tc.bind(ctx, &u)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantUser, u)
+		})
+	}
+}
+
+func TestContext_BindForm_multipart(t *testing.T) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	_ = writer.WriteField("name", "Tom")
+	_ = writer.WriteField("age", "18")
+	_ = writer.Close()
+	req := httptest.NewRequest(http.MethodPost, "/user", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	ctx := &Context{Req: req, Resp: httptest.NewRecorder()}
+	var u bindUser
+	assert.NoError(t, ctx.Bind(&u))
+	assert.Equal(t, bindUser{Name: "Tom", Age: 18}, u)
+
+	// 严格模式同样检查 multipart/form-data 里面的字段
+	body = &bytes.Buffer{}
+	writer = multipart.NewWriter(body)
+	_ = writer.WriteField("name", "Tom")
+	_ = writer.WriteField("extra", "1")
+	_ = writer.Close()
+	req = httptest.NewRequest(http.MethodPost, "/user?utm_source=x", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	ctx = &Context{Req: req, Resp: httptest.NewRecorder(), strictBinding: true}
+	err := ctx.Bind(&bindUser{})
+	assert.Equal(t, http.StatusBadRequest, err.(*HTTPError).Code)
+	assert.Equal(t, "字段 extra: 未知字段", err.(*HTTPError).Message)
+}
+
+type bindLevel int
+
+func (l *bindLevel) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return assert.AnError
+	}
+	return nil
+}
+
+type bindBase struct {
+	ID uint64 `form:"id"`
+}
+
+func Test_bindValues(t *testing.T) {
+	type query struct {
+		bindBase
+		Ratio    float64       `form:"ratio"`
+		Enabled  bool          `form:"enabled"`
+		Timeout  time.Duration `form:"timeout"`
+		Level    bindLevel     `form:"level"`
+		Levels   []bindLevel   `form:"levels"`
+		Ignored  string        `form:"-"`
+		NoTag    int8
+		Optional *int `form:"optional,omitempty"`
+		private  string
+	}
+	var q query
+	err := bindValues(&q, "form", url.Values{
+		"id":      {"12"},
+		"ratio":   {"0.5"},
+		"enabled": {"true"},
+		"timeout": {"1m"},
+		"level":   {"high"},
+		"levels":  {"low", "high"},
+		"Ignored": {"abc"},
+		"NoTag":   {"7"},
+	}, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, query{
+		bindBase: bindBase{ID: 12},
+		Ratio:    0.5,
+		Enabled:  true,
+		Timeout:  time.Minute,
+		Level:    2,
+		Levels:   []bindLevel{1, 2},
+		NoTag:    7,
+	}, q)
+
+	err = bindValues(&q, "form", url.Values{"NoTag": {"300"}}, nil, nil)
+	assert.EqualError(t, err, `字段 NoTag 的值 "300": strconv.ParseInt: parsing "300": value out of range`)
+	err = bindValues(&q, "form", url.Values{"level": {"middle"}}, nil, nil)
+	assert.ErrorIs(t, err, assert.AnError)
+	private := url.Values{"private": {"abc"}}
+	err = bindValues(&q, "form", private, nil, private)
+	assert.EqualError(t, err, "字段 private: 未知字段")
+	// 只检查 strictValues 里面的字段
+	err = bindValues(&q, "form", private, nil, url.Values{"id": {"1"}})
+	assert.NoError(t, err)
+	err = bindValues(q, "form", url.Values{}, nil, nil)
+	assert.Error(t, err)
+}
+
+func TestContext_BindQueryHeaderPath(t *testing.T) {
+	type query struct {
+		Page   int         `query:"page" default:"1"`
+		Size   *int        `query:"size"`
+		Sort   []string    `query:"sort" default:"id,name"`
+		Levels []bindLevel `query:"level"`
+		Since  *time.Time  `query:"since"`
+	}
+	type header struct {
+		RequestID string        `header:"x-request-id"`
+		Timeout   time.Duration `header:"X-Timeout" default:"1s"`
+		Langs     []string      `header:"Accept-Language"`
+	}
+	type path struct {
+		ID   uint64 `path:"id"`
+		Name string `path:"name" default:"anonymous"`
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/user/123?size=20&level=low&level=high&since=2022-08-01T00:00:00Z", nil)
+	req.Header.Set("X-Request-Id", "req-1")
+	req.Header.Add("Accept-Language", "zh-CN")
+	req.Header.Add("Accept-Language", "en")
+	ctx := &Context{Req: req, PathParams: map[string]string{"id": "123"}}
+
+	var q query
+	assert.NoError(t, ctx.BindQuery(&q))
+	size := 20
+	since := time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, query{
+		Page:   1,
+		Size:   &size,
+		Sort:   []string{"id", "name"},
+		Levels: []bindLevel{1, 2},
+		Since:  &since,
+	}, q)
+
+	var h header
+	assert.NoError(t, ctx.BindHeader(&h))
+	assert.Equal(t, header{RequestID: "req-1", Timeout: time.Second, Langs: []string{"zh-CN", "en"}}, h)
+
+	var p path
+	assert.NoError(t, ctx.BindPath(&p))
+	assert.Equal(t, path{ID: 123, Name: "anonymous"}, p)
+
+	ctx.PathParams["id"] = "abc"
+	err := ctx.BindPath(&p)
+	var httpErr *HTTPError
+	assert.ErrorAs(t, err, &httpErr)
+	assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+	assert.Equal(t, `字段 id 的值 "abc": strconv.ParseUint: parsing "abc": invalid syntax`, httpErr.Message)
+}
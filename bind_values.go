@@ -0,0 +1,189 @@
+package web
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BindError 是把字符串绑定到结构体字段上失败时候的 error
+type BindError struct {
+	// Field 出错的字段，是标签里面的名字
+	Field string
+	// Value 出错的值
+	Value string
+	Err   error
+}
+
+func (e *BindError) Error() string {
+	if e.Value == "" {
+		return fmt.Sprintf("字段 %s: %v", e.Field, e.Err)
+	}
+	return fmt.Sprintf("字段 %s 的值 %q: %v", e.Field, e.Value, e.Err)
+}
+
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+var errUnknownField = errors.New("未知字段")
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+)
+
+// bindValues 把 values 按照 tag 绑定到 dst 上，dst 必须是指向结构体的指针
+// values 里面没有的字段，如果有 default 标签，会使用 default 标签的值，切片用逗号分隔多个默认值。
+// canonical 不为 nil 的时候，会用它转换字段名之后再到 values 里面查找，例如请求头。
+// strictValues 不为 nil 的时候是严格模式，strictValues 里面有 dst 没有的字段会返回 error，
+// 它一般是 values 里面需要检查的那一部分，例如表单里面只检查请求体，不检查查询参数
+func bindValues(dst any, tag string, values map[string][]string, canonical func(string) string,
+	strictValues map[string][]string) error {
+	val := reflect.ValueOf(dst)
+	if val.Kind() != reflect.Pointer || val.IsNil() || val.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("web: 只能绑定到指向结构体的指针上，实际是 %T", dst)
+	}
+	known := make(map[string]struct{}, 8)
+	if err := bindStruct(val.Elem(), tag, values, canonical, known); err != nil {
+		return err
+	}
+	if strictValues != nil {
+		for key := range strictValues {
+			if _, ok := known[key]; !ok {
+				return &BindError{Field: key, Err: errUnknownField}
+			}
+		}
+	}
+	return nil
+}
+
+func bindStruct(val reflect.Value, tag string, values map[string][]string,
+	canonical func(string) string, known map[string]struct{}) error {
+	typ := val.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		fd := typ.Field(i)
+		fv := val.Field(i)
+		// 嵌入的结构体，把它的字段当作自己的字段
+		if fd.Anonymous && fd.Type.Kind() == reflect.Struct && fd.Tag.Get(tag) == "" {
+			if err := bindStruct(fv, tag, values, canonical, known); err != nil {
+				return err
+			}
+			continue
+		}
+		name, ok := fieldName(fd, tag)
+		if !ok {
+			continue
+		}
+		if canonical != nil {
+			name = canonical(name)
+		}
+		known[name] = struct{}{}
+		vals := values[name]
+		if len(vals) == 0 {
+			def, ok := fd.Tag.Lookup("default")
+			if !ok {
+				continue
+			}
+			vals = []string{def}
+			if fd.Type.Kind() == reflect.Slice {
+				vals = strings.Split(def, ",")
+			}
+		}
+		if err := setField(fv, vals); err != nil {
+			return &BindError{Field: name, Value: strings.Join(vals, ","), Err: err}
+		}
+	}
+	return nil
+}
+
+// fieldName 返回字段绑定时候的名字，第二个返回值为 false 代表忽略这个字段
+func fieldName(fd reflect.StructField, tag string) (string, bool) {
+	if !fd.IsExported() {
+		return "", false
+	}
+	name := fd.Tag.Get(tag)
+	if idx := strings.IndexByte(name, ','); idx >= 0 {
+		name = name[:idx]
+	}
+	if name == "-" {
+		return "", false
+	}
+	if name == "" {
+		name = fd.Name
+	}
+	return name, true
+}
+
+// setField 把 vals 设置到 fv 上，除了切片以外都只使用第一个值
+func setField(fv reflect.Value, vals []string) error {
+	if fv.CanAddr() && fv.Addr().Type().Implements(textUnmarshalerType) {
+		return fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(vals[0]))
+	}
+	switch fv.Kind() {
+	case reflect.Pointer:
+		elem := reflect.New(fv.Type().Elem())
+		if err := setField(elem.Elem(), vals); err != nil {
+			return err
+		}
+		fv.Set(elem)
+		return nil
+	case reflect.Slice:
+		slice := reflect.MakeSlice(fv.Type(), len(vals), len(vals))
+		for i, v := range vals {
+			if err := setField(slice.Index(i), []string{v}); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+		return nil
+	default:
+		return setScalar(fv, vals[0])
+	}
+}
+
+func setScalar(fv reflect.Value, str string) error {
+	if fv.Type() == durationType {
+		d, err := time.ParseDuration(str)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(str)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(str)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(str, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(str, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(str, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	default:
+		return fmt.Errorf("不支持的类型 %s", fv.Type())
+	}
+	return nil
+}
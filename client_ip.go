@@ -0,0 +1,146 @@
+package web
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ServerWithTrustedProxies 设置可信的代理，可以是 CIDR，例如 10.0.0.0/8，也可以是单个 IP
+// 只有来自可信代理的请求，Context.ClientIP 才会使用 ServerWithClientIPHeader 设置的请求头。
+// 非法的地址会 panic
+func ServerWithTrustedProxies(proxies ...string) HTTPServerOption {
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+	for _, proxy := range proxies {
+		prefix, err := parsePrefix(proxy)
+		if err != nil {
+			panic(fmt.Sprintf("web: 非法的代理地址 %s", proxy))
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return func(server *HTTPServer) {
+		server.trustedProxies = append(server.trustedProxies, prefixes...)
+	}
+}
+
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// ServerWithClientIPHeader 设置 Context.ClientIP 使用的请求头，默认是 X-Forwarded-For
+// 只会使用这一个请求头，必须是可信代理会追加或者覆盖的请求头，否则客户端可以伪造自己的 IP。
+// 支持 Forwarded（使用里面的 for 参数），X-Forwarded-For，X-Real-IP 以及其它逗号分隔地址的请求头
+func ServerWithClientIPHeader(header string) HTTPServerOption {
+	return func(server *HTTPServer) {
+		server.clientIPHeader = http.CanonicalHeaderKey(header)
+	}
+}
+
+// ClientIP 返回客户端的 IP
+// Req.RemoteAddr 是可信代理的时候，从右往左读取 ServerWithClientIPHeader 设置的请求头，
+// 跳过可信代理，第一个不可信的地址就是客户端，所以客户端自己伪造的地址不会被使用。
+// 遇到非法地址的时候停下来，返回它右边的地址，也就是追加了这个非法地址的可信代理。
+// 其它情况使用 Req.RemoteAddr
+func (c *Context) ClientIP() string {
+	host, _, err := net.SplitHostPort(c.Req.RemoteAddr)
+	if err != nil {
+		host = c.Req.RemoteAddr
+	}
+	remote, err := netip.ParseAddr(host)
+	if err != nil {
+		return host
+	}
+	remote = remote.Unmap()
+	if !c.isTrustedProxy(remote) {
+		return remote.String()
+	}
+	header := c.clientIPHeader
+	if header == "" {
+		header = "X-Forwarded-For"
+	}
+	var values []string
+	if header == "Forwarded" {
+		values = forwardedFor(c.Req.Header.Values(header))
+	} else {
+		values = splitHeaderValues(c.Req.Header.Values(header))
+	}
+	res := remote
+	for i := len(values) - 1; i >= 0; i-- {
+		addr, err := parseForwardedAddr(values[i])
+		if err != nil {
+			break
+		}
+		res = addr
+		if !c.isTrustedProxy(addr) {
+			break
+		}
+	}
+	return res.String()
+}
+
+func (c *Context) isTrustedProxy(addr netip.Addr) bool {
+	for _, prefix := range c.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedFor 返回 Forwarded 请求头里面所有的 for 参数，参考 RFC 7239
+// 例如 for=192.0.2.60;proto=http, for="[2001:db8::1]:4711"
+func forwardedFor(headers []string) []string {
+	var res []string
+	for _, elem := range splitHeaderValues(headers) {
+		for _, pair := range strings.Split(elem, ";") {
+			k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if ok && strings.EqualFold(k, "for") {
+				res = append(res, strings.Trim(v, `"`))
+			}
+		}
+	}
+	return res
+}
+
+// splitHeaderValues 按照逗号切分多个请求头，去掉空格和空的值
+func splitHeaderValues(headers []string) []string {
+	var res []string
+	for _, header := range headers {
+		for _, val := range strings.Split(header, ",") {
+			if val = strings.TrimSpace(val); val != "" {
+				res = append(res, val)
+			}
+		}
+	}
+	return res
+}
+
+// parseForwardedAddr 解析请求头里面的地址，可以带端口，IPv6 带端口的时候要用方括号
+func parseForwardedAddr(s string) (netip.Addr, error) {
+	s = strings.TrimSpace(s)
+	if addrPort, err := netip.ParseAddrPort(s); err == nil {
+		return addrPort.Addr().Unmap(), nil
+	}
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]"))
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	return addr.Unmap(), nil
+}
@@ -0,0 +1,235 @@
+package web
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContext_ClientIP(t *testing.T) {
+	testCases := []struct {
+		name       string
+		proxies    []string
+		header     string
+		remoteAddr string
+		headers    map[string][]string
+		wantIP     string
+	}{
+		{
+			name:       "no proxy",
+			remoteAddr: "203.0.113.5:1234",
+			wantIP:     "203.0.113.5",
+		},
+		{
+			name:       "remote addr without port",
+			remoteAddr: "203.0.113.5",
+			wantIP:     "203.0.113.5",
+		},
+		{
+			// 没有设置可信代理的时候，客户端伪造的请求头不会被使用
+			name:       "spoofed without trusted proxies",
+			remoteAddr: "203.0.113.5:1234",
+			headers: map[string][]string{
+				"X-Forwarded-For": {"1.1.1.1"},
+				"X-Real-Ip":       {"1.1.1.1"},
+				"Forwarded":       {"for=1.1.1.1"},
+			},
+			wantIP: "203.0.113.5",
+		},
+		{
+			// 请求不是来自可信代理
+			name:       "spoofed from untrusted remote",
+			proxies:    []string{"10.0.0.0/8"},
+			remoteAddr: "203.0.113.5:1234",
+			headers: map[string][]string{
+				"X-Forwarded-For": {"1.1.1.1"},
+			},
+			wantIP: "203.0.113.5",
+		},
+		{
+			name:       "x-forwarded-for",
+			proxies:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.1:1234",
+			headers: map[string][]string{
+				"X-Forwarded-For": {"203.0.113.5"},
+			},
+			wantIP: "203.0.113.5",
+		},
+		{
+			// 客户端自己加了一个地址，代理在后面追加了真正的地址
+			name:       "x-forwarded-for spoofed by client",
+			proxies:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.1:1234",
+			headers: map[string][]string{
+				"X-Forwarded-For": {"1.1.1.1, 203.0.113.5"},
+			},
+			wantIP: "203.0.113.5",
+		},
+		{
+			name:       "x-forwarded-for multiple proxies",
+			proxies:    []string{"10.0.0.0/8", "192.168.1.1"},
+			remoteAddr: "10.0.0.1:1234",
+			headers: map[string][]string{
+				"X-Forwarded-For": {"1.1.1.1, 203.0.113.5", "192.168.1.1,10.0.0.2"},
+			},
+			wantIP: "203.0.113.5",
+		},
+		{
+			name:       "x-forwarded-for all trusted",
+			proxies:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.1:1234",
+			headers: map[string][]string{
+				"X-Forwarded-For": {"10.0.0.3, 10.0.0.2"},
+			},
+			wantIP: "10.0.0.3",
+		},
+		{
+			// 遇到非法地址就停下来，不会改用客户端能够伪造的其它请求头
+			name:       "x-forwarded-for invalid",
+			proxies:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.1:1234",
+			headers: map[string][]string{
+				"X-Forwarded-For": {"junk, 203.0.113.5"},
+				"X-Real-Ip":       {"6.6.6.6"},
+			},
+			wantIP: "203.0.113.5",
+		},
+		{
+			// 非法地址右边是可信代理，返回这个代理
+			name:       "x-forwarded-for invalid after trusted proxy",
+			proxies:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.1:1234",
+			headers: map[string][]string{
+				"X-Forwarded-For": {"203.0.113.5, junk, 10.0.0.2"},
+			},
+			wantIP: "10.0.0.2",
+		},
+		{
+			name:       "x-forwarded-for invalid only",
+			proxies:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.1:1234",
+			headers: map[string][]string{
+				"X-Forwarded-For": {"junk"},
+			},
+			wantIP: "10.0.0.1",
+		},
+		{
+			// 代理只追加 X-Forwarded-For，客户端伪造的 Forwarded 不会被使用
+			name:       "injected forwarded",
+			proxies:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.1:1234",
+			headers: map[string][]string{
+				"Forwarded":       {"for=6.6.6.6"},
+				"X-Forwarded-For": {"203.0.113.5"},
+			},
+			wantIP: "203.0.113.5",
+		},
+		{
+			name:       "injected x-real-ip",
+			proxies:    []string{"10.0.0.0/8"},
+			remoteAddr: "10.0.0.1:1234",
+			headers: map[string][]string{
+				"X-Real-Ip": {"6.6.6.6"},
+			},
+			wantIP: "10.0.0.1",
+		},
+		{
+			name:       "forwarded",
+			proxies:    []string{"10.0.0.0/8"},
+			header:     "Forwarded",
+			remoteAddr: "10.0.0.1:1234",
+			headers: map[string][]string{
+				"Forwarded":       {`for=1.1.1.1, For="[2001:db8::1]:4711";proto=https;by=10.0.0.1`},
+				"X-Forwarded-For": {"6.6.6.6"},
+			},
+			wantIP: "2001:db8::1",
+		},
+		{
+			name:       "forwarded unknown",
+			proxies:    []string{"10.0.0.0/8"},
+			header:     "forwarded",
+			remoteAddr: "10.0.0.1:1234",
+			headers: map[string][]string{
+				"Forwarded":       {"for=unknown"},
+				"X-Forwarded-For": {"203.0.113.5"},
+			},
+			wantIP: "10.0.0.1",
+		},
+		{
+			name:       "x-real-ip",
+			proxies:    []string{"10.0.0.0/8"},
+			header:     "X-Real-IP",
+			remoteAddr: "10.0.0.1:1234",
+			headers: map[string][]string{
+				"X-Real-Ip":       {"203.0.113.5:8080"},
+				"X-Forwarded-For": {"6.6.6.6"},
+			},
+			wantIP: "203.0.113.5",
+		},
+		{
+			name:       "x-real-ip invalid",
+			proxies:    []string{"10.0.0.0/8"},
+			header:     "X-Real-IP",
+			remoteAddr: "10.0.0.1:1234",
+			headers: map[string][]string{
+				"X-Real-Ip": {"abc"},
+			},
+			wantIP: "10.0.0.1",
+		},
+		{
+			name:       "ipv6 proxy",
+			proxies:    []string{"2001:db8::/32"},
+			remoteAddr: "[2001:db8::2]:1234",
+			headers: map[string][]string{
+				"X-Forwarded-For": {"::ffff:203.0.113.5"},
+			},
+			wantIP: "203.0.113.5",
+		},
+		{
+			name:       "ipv4 mapped remote addr",
+			proxies:    []string{"10.0.0.1"},
+			remoteAddr: "[::ffff:10.0.0.1]:1234",
+			headers: map[string][]string{
+				"X-Forwarded-For": {"203.0.113.5"},
+			},
+			wantIP: "203.0.113.5",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := []HTTPServerOption{ServerWithTrustedProxies(tc.proxies...)}
+			if tc.header != "" {
+				opts = append(opts, ServerWithClientIPHeader(tc.header))
+			}
+			s := NewHTTPServer(opts...)
+			var ip string
+			s.Get("/", func(ctx *Context) {
+				ip = ctx.ClientIP()
+			})
+			req, err := http.NewRequest(http.MethodGet, "/", nil)
+			require.NoError(t, err)
+			req.RemoteAddr = tc.remoteAddr
+			for k, vals := range tc.headers {
+				for _, v := range vals {
+					req.Header.Add(k, v)
+				}
+			}
+			s.ServeHTTP(httptest.NewRecorder(), req)
+			assert.Equal(t, tc.wantIP, ip)
+		})
+	}
+}
+
+func TestServerWithTrustedProxies(t *testing.T) {
+	s := NewHTTPServer(ServerWithTrustedProxies("10.1.2.3/8", "192.168.1.1", "::ffff:172.16.0.0/108"))
+	assert.Equal(t, "[10.0.0.0/8 192.168.1.1/32 172.16.0.0/12]", fmt.Sprint(s.trustedProxies))
+	assert.PanicsWithValue(t, "web: 非法的代理地址 10.0.0.0/33", func() {
+		ServerWithTrustedProxies("10.0.0.0/33")
+	})
+	assert.PanicsWithValue(t, "web: 非法的代理地址 abc", func() {
+		ServerWithTrustedProxies("abc")
+	})
+}
@@ -0,0 +1,113 @@
+package web
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Codec 负责某一种媒体类型的序列化和反序列化
+// 通过 ServerWithCodec 注册之后，Bind 和 Encode 都会使用它
+type Codec interface {
+	// Marshal 把 val 序列化，作为响应体
+	Marshal(val any) ([]byte, error)
+	// Decode 从 r 里面读取请求体，反序列化到 val 上
+	// strict 为 true 的时候，遇到 val 没有的字段应该返回 error，不支持严格模式的 Codec 可以忽略它
+	Decode(r io.Reader, val any, strict bool) error
+}
+
+// JSONCodec 基于 encoding/json 的 Codec
+type JSONCodec struct{}
+
+func (JSONCodec) Marshal(val any) ([]byte, error) {
+	return json.Marshal(val)
+}
+
+func (JSONCodec) Decode(r io.Reader, val any, strict bool) error {
+	decoder := json.NewDecoder(r)
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+	return decoder.Decode(val)
+}
+
+// XMLCodec 基于 encoding/xml 的 Codec，序列化的结果带有 XML 声明
+type XMLCodec struct{}
+
+func (XMLCodec) Marshal(val any) ([]byte, error) {
+	data, err := xml.Marshal(val)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+func (XMLCodec) Decode(r io.Reader, val any, _ bool) error {
+	return xml.NewDecoder(r).Decode(val)
+}
+
+// defaultCodecs 默认注册的 Codec，每次都返回一个新的 map
+func defaultCodecs() map[string]Codec {
+	return map[string]Codec{
+		"application/json": JSONCodec{},
+		"application/xml":  XMLCodec{},
+		"text/xml":         XMLCodec{},
+	}
+}
+
+// ServerWithCodec 注册 mediaType 对应的 Codec，覆盖已有的 Codec
+// mediaType 不带参数，例如 application/msgpack。codec 为 nil 的时候删除 mediaType 对应的 Codec。
+// 默认注册了 application/json，application/xml 和 text/xml
+func ServerWithCodec(mediaType string, codec Codec) HTTPServerOption {
+	return func(server *HTTPServer) {
+		mediaType = strings.ToLower(mediaType)
+		if codec == nil {
+			delete(server.codecs, mediaType)
+			return
+		}
+		server.codecs[mediaType] = codec
+	}
+}
+
+// codec 查找 mediaType 对应的 Codec
+// 没有注册的 +json 和 +xml 后缀的媒体类型，例如 application/problem+json，使用 JSON 和 XML 的 Codec
+func (c *Context) codec(mediaType string) (Codec, bool) {
+	codecs := c.codecs
+	if codecs == nil {
+		codecs = defaultCodecs()
+	}
+	mediaType = strings.ToLower(mediaType)
+	if codec, ok := codecs[mediaType]; ok {
+		return codec, true
+	}
+	switch {
+	case strings.HasSuffix(mediaType, "+json"):
+		codec, ok := codecs["application/json"]
+		return codec, ok
+	case strings.HasSuffix(mediaType, "+xml"):
+		codec, ok := codecs["application/xml"]
+		return codec, ok
+	default:
+		return nil, false
+	}
+}
+
+// Encode 使用 mediaType 对应的 Codec 序列化 val 作为响应，Content-Type 是 mediaType
+// 没有对应的 Codec 或者序列化失败的时候返回 error，并且不会修改响应
+func (c *Context) Encode(code int, mediaType string, val any) error {
+	return c.encode(code, mediaType, mediaType, val)
+}
+
+func (c *Context) encode(code int, mediaType string, contentType string, val any) error {
+	codec, ok := c.codec(mediaType)
+	if !ok {
+		return fmt.Errorf("web: 没有 %s 对应的 Codec", mediaType)
+	}
+	data, err := codec.Marshal(val)
+	if err != nil {
+		return err
+	}
+	return c.Data(code, contentType, data)
+}
@@ -0,0 +1,152 @@
+package web
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// kvCodec 一行一个 key=value，只支持 map[string]string
+type kvCodec struct{}
+
+func (kvCodec) Marshal(val any) ([]byte, error) {
+	m, ok := val.(map[string]string)
+	if !ok {
+		return nil, errors.New("只支持 map[string]string")
+	}
+	var buf bytes.Buffer
+	for _, k := range []string{"id", "name"} {
+		if v, ok := m[k]; ok {
+			buf.WriteString(k + "=" + v + "\n")
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func (kvCodec) Decode(r io.Reader, val any, strict bool) error {
+	m, ok := val.(*map[string]string)
+	if !ok {
+		return errors.New("只支持 *map[string]string")
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	*m = map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		k, v, ok := strings.Cut(line, "=")
+		if !ok {
+			return errors.New("非法的行 " + line)
+		}
+		(*m)[k] = v
+	}
+	return nil
+}
+
+func TestServerWithCodec(t *testing.T) {
+	testCases := []struct {
+		name        string
+		opts        []HTTPServerOption
+		contentType string
+		body        string
+		wantCode    int
+		wantType    string
+		wantBody    string
+	}{
+		{
+			name:        "custom codec",
+			opts:        []HTTPServerOption{ServerWithCodec("application/x-kv", kvCodec{})},
+			contentType: "application/x-kv; charset=utf-8",
+			body:        "id=1\nname=Tom",
+			wantCode:    http.StatusOK,
+			wantType:    "application/x-kv",
+			wantBody:    "id=1\nname=Tom\n",
+		},
+		{
+			name:        "media type case insensitive",
+			opts:        []HTTPServerOption{ServerWithCodec("Application/X-KV", kvCodec{})},
+			contentType: "application/x-kv",
+			body:        "id=1",
+			wantCode:    http.StatusOK,
+			wantType:    "application/x-kv",
+			wantBody:    "id=1\n",
+		},
+		{
+			name:        "decode error",
+			opts:        []HTTPServerOption{ServerWithCodec("application/x-kv", kvCodec{})},
+			contentType: "application/x-kv",
+			body:        "abc",
+			wantCode:    http.StatusBadRequest,
+			wantBody:    "非法的 application/x-kv 请求",
+		},
+		{
+			name:        "not registered",
+			contentType: "application/x-kv",
+			body:        "id=1",
+			wantCode:    http.StatusUnsupportedMediaType,
+			wantBody:    "不支持的 Content-Type application/x-kv",
+		},
+		{
+			name: "removed",
+			opts: []HTTPServerOption{
+				ServerWithCodec("application/x-kv", kvCodec{}),
+				ServerWithCodec("application/x-kv", nil),
+			},
+			contentType: "application/x-kv",
+			body:        "id=1",
+			wantCode:    http.StatusUnsupportedMediaType,
+			wantBody:    "不支持的 Content-Type application/x-kv",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewHTTPServer(tc.opts...)
+			s.PostErr("/", func(ctx *Context) error {
+				var m map[string]string
+				if err := ctx.Bind(&m); err != nil {
+					return err
+				}
+				return ctx.Encode(http.StatusOK, "application/x-kv", m)
+			})
+			req, err := http.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", tc.contentType)
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+			if tc.wantType != "" {
+				assert.Equal(t, tc.wantType, recorder.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestContext_Encode(t *testing.T) {
+	s := NewHTTPServer(ServerWithCodec("application/json", kvCodec{}))
+	ctx := &Context{Resp: httptest.NewRecorder(), codecs: s.codecs}
+
+	// 替换了 JSON 的 Codec 之后，JSON 也使用新的 Codec
+	require.NoError(t, ctx.JSON(http.StatusOK, map[string]string{"id": "1"}))
+	assert.Equal(t, "id=1\n", string(ctx.RespData))
+	assert.Equal(t, "application/json; charset=utf-8", ctx.Resp.Header().Get("Content-Type"))
+
+	// 没有注册的 +json 后缀使用 JSON 的 Codec
+	require.NoError(t, ctx.Encode(http.StatusOK, "application/vnd.api+json", map[string]string{"id": "2"}))
+	assert.Equal(t, "id=2\n", string(ctx.RespData))
+	assert.Equal(t, "application/vnd.api+json", ctx.Resp.Header().Get("Content-Type"))
+
+	// 序列化失败不修改响应
+	assert.Error(t, ctx.Encode(http.StatusCreated, "application/json", 123))
+	assert.Equal(t, http.StatusOK, ctx.RespStatusCode)
+	assert.Equal(t, "id=2\n", string(ctx.RespData))
+
+	err := ctx.Encode(http.StatusOK, "application/msgpack", 123)
+	assert.Equal(t, errors.New("web: 没有 application/msgpack 对应的 Codec"), err)
+}
@@ -1,9 +1,48 @@
 package web
 
-import "net/http"
+import (
+	"net/http"
+	"net/netip"
+	"net/url"
+)
 
 type Context struct {
-	Req        *http.Request
+	Req *http.Request
+	// Resp 是 HTTPServer 封装过的 ResponseWriter
+	// 可以用 WrapResponseWriter(ctx.Resp) 拿到响应码和写入的字节数
 	Resp       http.ResponseWriter
 	PathParams map[string]string
+	// RouteMeta 命中路由的元数据，没有命中路由的时候是一个空的 RouteMeta，不会是 nil
+	// 多个请求共享同一个 RouteMeta，不能修改
+	RouteMeta *RouteMeta
+
+	// MatchedRoute 命中的路由，例如 /user/:id
+	// 适合用作 metrics 和日志的维度，避免使用原始 URL 导致维度爆炸
+	MatchedRoute string
+	// MatchedRouteType 命中路由最后一段的类型
+	MatchedRouteType RouteType
+
+	// RespStatusCode 和 RespData 是缓存起来的响应
+	// HTTPServer 会在所有的 middleware 执行完毕之后才把它们写到 Resp 里面，
+	// 所以 middleware 可以在 next 返回之后修改响应码，响应头和响应体。
+	// 直接调用 Resp.Write 写入的数据不会被缓存
+	RespStatusCode int
+	RespData       []byte
+
+	// bodyLimit 和 strictBinding 来自 HTTPServer 的配置，绑定请求的时候使用
+	bodyLimit     int64
+	strictBinding bool
+	// codecs 来自 HTTPServer，只读
+	codecs map[string]Codec
+	// tplEngine 来自 HTTPServer 的模板引擎
+	tplEngine TemplateEngine
+	// trustedProxies 来自 HTTPServer 的可信代理
+	trustedProxies []netip.Prefix
+	// clientIPHeader 来自 HTTPServer，Context.ClientIP 使用的请求头
+	clientIPHeader string
+
+	// queryCache 缓存解析之后的查询参数
+	queryCache url.Values
+	// values 通过 Set 保存的值
+	values map[any]any
 }
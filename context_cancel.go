@@ -0,0 +1,54 @@
+package web
+
+import (
+	"context"
+	"time"
+)
+
+// 确保 Context 实现了 context.Context，可以直接传给数据库之类的调用
+var _ context.Context = &Context{}
+
+// Deadline 返回 Req.Context() 的截止时间
+func (c *Context) Deadline() (time.Time, bool) {
+	if c.Req == nil {
+		return time.Time{}, false
+	}
+	return c.Req.Context().Deadline()
+}
+
+// Done 返回 Req.Context() 的 Done，客户端断开连接或者超时的时候会被关闭
+func (c *Context) Done() <-chan struct{} {
+	if c.Req == nil {
+		return nil
+	}
+	return c.Req.Context().Done()
+}
+
+// Err 返回 Req.Context() 的 Err
+func (c *Context) Err() error {
+	if c.Req == nil {
+		return nil
+	}
+	return c.Req.Context().Err()
+}
+
+// Value 返回 key 对应的值，参考 Get
+func (c *Context) Value(key any) any {
+	val, _ := c.Get(key)
+	return val
+}
+
+// WithTimeout 给请求设置超时时间，超时之后 Done 会被关闭，Err 返回 context.DeadlineExceeded
+// 它会替换 Req，所以后续拿到的 Req.Context() 也会超时。
+// 和 context.WithTimeout 一样，调用者要负责调用返回的 cancel，一般是 defer cancel()
+func (c *Context) WithTimeout(timeout time.Duration) context.CancelFunc {
+	return c.WithDeadline(time.Now().Add(timeout))
+}
+
+// WithDeadline 和 WithTimeout 一样，只是指定的是截止时间
+// 已有的截止时间更早的时候，不会被推迟
+func (c *Context) WithDeadline(deadline time.Time) context.CancelFunc {
+	reqCtx, cancel := context.WithDeadline(c.Req.Context(), deadline)
+	c.Req = c.Req.WithContext(reqCtx)
+	return cancel
+}
@@ -0,0 +1,90 @@
+package web
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestContext_context(t *testing.T) {
+	reqCtx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, "/", nil)
+	require.NoError(t, err)
+	ctx := &Context{Req: req}
+	ctx.Set(testCtxKey("user"), "Tom")
+
+	// 可以直接当作 context.Context 使用
+	var c context.Context = ctx
+	assert.Equal(t, "Tom", c.Value(testCtxKey("user")))
+	assert.Nil(t, c.Value(testCtxKey("abc")))
+	_, ok := c.Deadline()
+	assert.False(t, ok)
+	assert.NoError(t, c.Err())
+
+	cancel()
+	<-c.Done()
+	assert.Equal(t, context.Canceled, c.Err())
+
+	empty := &Context{}
+	_, ok = empty.Deadline()
+	assert.False(t, ok)
+	assert.Nil(t, empty.Done())
+	assert.NoError(t, empty.Err())
+	assert.Nil(t, empty.Value("abc"))
+}
+
+func TestContext_WithTimeout(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "/", nil)
+	require.NoError(t, err)
+	ctx := &Context{Req: req}
+
+	cancel := ctx.WithTimeout(time.Hour)
+	deadline, ok := ctx.Deadline()
+	assert.True(t, ok)
+	// 更晚的截止时间不会推迟已有的截止时间
+	defer ctx.WithDeadline(deadline.Add(time.Hour))()
+	newDeadline, _ := ctx.Req.Context().Deadline()
+	assert.Equal(t, deadline, newDeadline)
+
+	cancel()
+	<-ctx.Done()
+	assert.Equal(t, context.Canceled, ctx.Err())
+}
+
+func TestHTTPServer_routeTimeout(t *testing.T) {
+	s := NewHTTPServer()
+	var (
+		hasDeadline bool
+		err         error
+	)
+	s.Get("/slow", func(ctx *Context) {
+		_, hasDeadline = ctx.Deadline()
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			ctx.RespStatusCode = http.StatusServiceUnavailable
+		case <-time.After(time.Second):
+			ctx.RespStatusCode = http.StatusOK
+		}
+	}, RouteWithTimeout(10*time.Millisecond))
+	s.Get("/fast", func(ctx *Context) {
+		_, hasDeadline = ctx.Deadline()
+	})
+
+	req, reqErr := http.NewRequest(http.MethodGet, "/slow", nil)
+	require.NoError(t, reqErr)
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, req)
+	assert.True(t, hasDeadline)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+
+	req, reqErr = http.NewRequest(http.MethodGet, "/fast", nil)
+	require.NoError(t, reqErr)
+	s.ServeHTTP(httptest.NewRecorder(), req)
+	assert.False(t, hasDeadline)
+}
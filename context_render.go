@@ -0,0 +1,62 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// 下面的方法都只是设置 RespStatusCode，RespData 和 Content-Type，
+// 在所有的 middleware 执行完毕之后，HTTPServer 才会真正写入响应，并且设置 Content-Length
+
+// JSON 使用 application/json 对应的 Codec 把 val 序列化成 JSON 作为响应
+// 序列化失败的时候返回 error，并且不会修改响应
+func (c *Context) JSON(code int, val any) error {
+	return c.encode(code, "application/json", "application/json; charset=utf-8", val)
+}
+
+// XML 使用 application/xml 对应的 Codec 把 val 序列化成 XML 作为响应
+// 序列化失败的时候返回 error，并且不会修改响应
+func (c *Context) XML(code int, val any) error {
+	return c.encode(code, "application/xml", "application/xml; charset=utf-8", val)
+}
+
+// String 返回纯文本，values 不为空的时候按照 fmt.Sprintf 格式化
+func (c *Context) String(code int, format string, values ...any) error {
+	if len(values) > 0 {
+		format = fmt.Sprintf(format, values...)
+	}
+	return c.Data(code, "text/plain; charset=utf-8", []byte(format))
+}
+
+// HTML 返回 HTML，html 不会被转义
+func (c *Context) HTML(code int, html string) error {
+	return c.Data(code, "text/html; charset=utf-8", []byte(html))
+}
+
+// Data 以 contentType 返回 data
+func (c *Context) Data(code int, contentType string, data []byte) error {
+	if contentType != "" {
+		c.Resp.Header().Set("Content-Type", contentType)
+	}
+	c.RespStatusCode = code
+	c.RespData = data
+	return nil
+}
+
+// NoContent 返回 204，没有响应体
+func (c *Context) NoContent() error {
+	c.RespStatusCode = http.StatusNoContent
+	c.RespData = nil
+	return nil
+}
+
+// Redirect 重定向到 url，code 必须是 3xx
+func (c *Context) Redirect(code int, url string) error {
+	if code < http.StatusMultipleChoices || code > http.StatusPermanentRedirect {
+		return fmt.Errorf("web: 重定向的响应码必须是 3xx，实际是 %d", code)
+	}
+	c.Resp.Header().Set("Location", url)
+	c.RespStatusCode = code
+	c.RespData = nil
+	return nil
+}
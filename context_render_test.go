@@ -0,0 +1,165 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContext_Render(t *testing.T) {
+	type User struct {
+		Name string `json:"name" xml:"name"`
+	}
+	testCases := []struct {
+		name        string
+		handler     HandleErrFunc
+		wantErr     bool
+		wantCode    int
+		wantType    string
+		wantLength  string
+		wantBody    string
+		wantHeaders map[string]string
+	}{
+		{
+			name: "json",
+			handler: func(ctx *Context) error {
+				return ctx.JSON(http.StatusCreated, User{Name: "Tom"})
+			},
+			wantCode:   http.StatusCreated,
+			wantType:   "application/json; charset=utf-8",
+			wantLength: "14",
+			wantBody:   `{"name":"Tom"}`,
+		},
+		{
+			name: "json error",
+			handler: func(ctx *Context) error {
+				return ctx.JSON(http.StatusOK, make(chan int))
+			},
+			wantErr:  true,
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "xml",
+			handler: func(ctx *Context) error {
+				return ctx.XML(http.StatusOK, User{Name: "Tom"})
+			},
+			wantCode:   http.StatusOK,
+			wantType:   "application/xml; charset=utf-8",
+			wantLength: "68",
+			wantBody:   "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<User><name>Tom</name></User>",
+		},
+		{
+			name: "xml error",
+			handler: func(ctx *Context) error {
+				return ctx.XML(http.StatusOK, func() {})
+			},
+			wantErr:  true,
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "string",
+			handler: func(ctx *Context) error {
+				return ctx.String(http.StatusOK, "hello, %s", "Tom")
+			},
+			wantCode:   http.StatusOK,
+			wantType:   "text/plain; charset=utf-8",
+			wantLength: "10",
+			wantBody:   "hello, Tom",
+		},
+		{
+			name: "string without values",
+			handler: func(ctx *Context) error {
+				return ctx.String(http.StatusOK, "100%")
+			},
+			wantCode:   http.StatusOK,
+			wantType:   "text/plain; charset=utf-8",
+			wantLength: "4",
+			wantBody:   "100%",
+		},
+		{
+			name: "html",
+			handler: func(ctx *Context) error {
+				return ctx.HTML(http.StatusOK, "<h1>你好</h1>")
+			},
+			wantCode:   http.StatusOK,
+			wantType:   "text/html; charset=utf-8",
+			wantLength: "15",
+			wantBody:   "<h1>你好</h1>",
+		},
+		{
+			name: "data",
+			handler: func(ctx *Context) error {
+				return ctx.Data(http.StatusOK, "application/octet-stream", []byte{1, 2, 3})
+			},
+			wantCode:   http.StatusOK,
+			wantType:   "application/octet-stream",
+			wantLength: "3",
+			wantBody:   "\x01\x02\x03",
+		},
+		{
+			name: "no content",
+			handler: func(ctx *Context) error {
+				return ctx.NoContent()
+			},
+			wantCode: http.StatusNoContent,
+		},
+		{
+			name: "redirect",
+			handler: func(ctx *Context) error {
+				return ctx.Redirect(http.StatusFound, "/login")
+			},
+			wantCode:    http.StatusFound,
+			wantHeaders: map[string]string{"Location": "/login"},
+		},
+		{
+			name: "redirect invalid code",
+			handler: func(ctx *Context) error {
+				return ctx.Redirect(http.StatusOK, "/login")
+			},
+			wantErr:  true,
+			wantCode: http.StatusOK,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var err error
+			s := NewHTTPServer()
+			s.Get("/", func(ctx *Context) {
+				err = tc.handler(ctx)
+			})
+			req, reqErr := http.NewRequest(http.MethodGet, "/", nil)
+			require.NoError(t, reqErr)
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantType, recorder.Header().Get("Content-Type"))
+			assert.Equal(t, tc.wantLength, recorder.Header().Get("Content-Length"))
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+			for k, v := range tc.wantHeaders {
+				assert.Equal(t, v, recorder.Header().Get(k))
+			}
+		})
+	}
+}
+
+func TestContext_RenderRewrittenByMiddleware(t *testing.T) {
+	// middleware 修改了响应体之后，Content-Length 也要跟着变
+	s := NewHTTPServer(ServerWithMiddleware(func(next HandleFunc) HandleFunc {
+		return func(ctx *Context) {
+			next(ctx)
+			ctx.RespData = append(ctx.RespData, "!!!"...)
+		}
+	}))
+	s.Get("/", func(ctx *Context) {
+		_ = ctx.String(http.StatusOK, "hello")
+	})
+	req, err := http.NewRequest(http.MethodGet, "/", nil)
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, req)
+	assert.Equal(t, "8", recorder.Header().Get("Content-Length"))
+	assert.Equal(t, "hello!!!", recorder.Body.String())
+}
@@ -0,0 +1,63 @@
+package web
+
+import (
+	"context"
+	"fmt"
+)
+
+// Set 保存 key 对应的值，用于在 middleware 和 handler 之间传递数据，例如登录的用户，租户
+// 值同时会通过 context.WithValue 放到 Req.Context() 里面，所以拿到 Req.Context() 的第三方库也能看到。
+// 和 context.WithValue 一样，key 应该使用自定义的类型，避免和别人冲突。
+// Context 不是线程安全的，不要在多个 goroutine 里面同时调用 Set
+func (c *Context) Set(key any, val any) {
+	if c.values == nil {
+		c.values = make(map[any]any, 4)
+	}
+	c.values[key] = val
+	if c.Req != nil {
+		c.Req = c.Req.WithContext(context.WithValue(c.Req.Context(), key, val))
+	}
+}
+
+// Get 返回 key 对应的值
+// 先查找通过 Set 保存的值，再查找 Req.Context() 里面的值
+func (c *Context) Get(key any) (any, bool) {
+	if val, ok := c.values[key]; ok {
+		return val, true
+	}
+	if c.Req == nil {
+		return nil, false
+	}
+	val := c.Req.Context().Value(key)
+	return val, val != nil
+}
+
+// Value 返回 key 对应的类型为 T 的值
+// 没有 key 对应的值，或者值的类型不是 T 的时候，第二个返回值是 false
+func Value[T any](ctx *Context, key any) (T, bool) {
+	val, ok := ctx.Get(key)
+	if !ok {
+		var t T
+		return t, false
+	}
+	t, ok := val.(T)
+	return t, ok
+}
+
+// ValueOr 和 Value 一样，但是没有类型为 T 的值的时候返回 def
+func ValueOr[T any](ctx *Context, key any, def T) T {
+	if t, ok := Value[T](ctx, key); ok {
+		return t
+	}
+	return def
+}
+
+// MustValue 和 Value 一样，但是没有类型为 T 的值的时候 panic
+// 适合一定会被 middleware 设置的值，例如鉴权之后的用户
+func MustValue[T any](ctx *Context, key any) T {
+	t, ok := Value[T](ctx, key)
+	if !ok {
+		panic(fmt.Sprintf("web: 没有 %v 对应的值，或者值的类型不对", key))
+	}
+	return t
+}
@@ -0,0 +1,74 @@
+package web
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type testCtxKey string
+
+type testUser struct {
+	Name string
+}
+
+func TestContext_SetGet(t *testing.T) {
+	const (
+		userKey   testCtxKey = "user"
+		tenantKey testCtxKey = "tenant"
+		traceKey  testCtxKey = "trace"
+	)
+	s := NewHTTPServer(ServerWithMiddleware(func(next HandleFunc) HandleFunc {
+		return func(ctx *Context) {
+			ctx.Set(userKey, &testUser{Name: "Tom"})
+			ctx.Set(tenantKey, 123)
+			next(ctx)
+		}
+	}))
+	var (
+		user      *testUser
+		reqUser   any
+		tenant    int
+		tenantStr string
+		trace     string
+		missing   bool
+	)
+	s.Get("/", func(ctx *Context) {
+		user, _ = Value[*testUser](ctx, userKey)
+		reqUser = ctx.Req.Context().Value(userKey)
+		tenant = MustValue[int](ctx, tenantKey)
+		tenantStr = ValueOr[string](ctx, tenantKey, "default")
+		trace, _ = Value[string](ctx, traceKey)
+		_, missing = ctx.Get(testCtxKey("abc"))
+	})
+
+	req, err := http.NewRequest(http.MethodGet, "/", nil)
+	require.NoError(t, err)
+	// 其它地方放到 Req.Context() 里面的值也能拿到
+	req = req.WithContext(context.WithValue(req.Context(), traceKey, "trace-1"))
+	s.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, &testUser{Name: "Tom"}, user)
+	assert.Same(t, user, reqUser)
+	assert.Equal(t, 123, tenant)
+	assert.Equal(t, "default", tenantStr)
+	assert.Equal(t, "trace-1", trace)
+	assert.False(t, missing)
+}
+
+func TestMustValue(t *testing.T) {
+	ctx := &Context{}
+	ctx.Set("id", 123)
+	val, ok := ctx.Get("id")
+	assert.True(t, ok)
+	assert.Equal(t, 123, val)
+	assert.PanicsWithValue(t, "web: 没有 id 对应的值，或者值的类型不对", func() {
+		MustValue[string](ctx, "id")
+	})
+	assert.PanicsWithValue(t, "web: 没有 name 对应的值，或者值的类型不对", func() {
+		MustValue[string](ctx, "name")
+	})
+}
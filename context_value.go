@@ -0,0 +1,181 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// ErrValueNotFound 代表没有这个参数
+var ErrValueNotFound = errors.New("web: 参数不存在")
+
+// StringValue 是从路径参数，查询参数或者请求头里面取出来的值
+// 用 AsXXX 方法转换成需要的类型，转换失败或者参数不存在的时候 Err 不为 nil
+type StringValue struct {
+	// Key 参数的名字，用于报错
+	Key string
+	Val string
+	Err error
+}
+
+func (s StringValue) String() (string, error) {
+	return s.Val, s.Err
+}
+
+// StringOr 参数不存在的时候返回 def
+func (s StringValue) StringOr(def string) string {
+	if s.Err != nil {
+		return def
+	}
+	return s.Val
+}
+
+func (s StringValue) AsInt64() (int64, error) {
+	if s.Err != nil {
+		return 0, s.Err
+	}
+	val, err := strconv.ParseInt(s.Val, 10, 64)
+	return val, s.wrap(err)
+}
+
+// AsInt64Or 参数不存在或者转换失败的时候返回 def
+func (s StringValue) AsInt64Or(def int64) int64 {
+	val, err := s.AsInt64()
+	if err != nil {
+		return def
+	}
+	return val
+}
+
+func (s StringValue) AsUint64() (uint64, error) {
+	if s.Err != nil {
+		return 0, s.Err
+	}
+	val, err := strconv.ParseUint(s.Val, 10, 64)
+	return val, s.wrap(err)
+}
+
+func (s StringValue) AsUint64Or(def uint64) uint64 {
+	val, err := s.AsUint64()
+	if err != nil {
+		return def
+	}
+	return val
+}
+
+func (s StringValue) AsFloat64() (float64, error) {
+	if s.Err != nil {
+		return 0, s.Err
+	}
+	val, err := strconv.ParseFloat(s.Val, 64)
+	return val, s.wrap(err)
+}
+
+func (s StringValue) AsFloat64Or(def float64) float64 {
+	val, err := s.AsFloat64()
+	if err != nil {
+		return def
+	}
+	return val
+}
+
+// AsBool 支持 strconv.ParseBool 能够识别的值，例如 1，t，true，0，f，false
+func (s StringValue) AsBool() (bool, error) {
+	if s.Err != nil {
+		return false, s.Err
+	}
+	val, err := strconv.ParseBool(s.Val)
+	return val, s.wrap(err)
+}
+
+func (s StringValue) AsBoolOr(def bool) bool {
+	val, err := s.AsBool()
+	if err != nil {
+		return def
+	}
+	return val
+}
+
+// AsDuration 支持 time.ParseDuration 能够识别的值，例如 1h30m
+func (s StringValue) AsDuration() (time.Duration, error) {
+	if s.Err != nil {
+		return 0, s.Err
+	}
+	val, err := time.ParseDuration(s.Val)
+	return val, s.wrap(err)
+}
+
+func (s StringValue) AsDurationOr(def time.Duration) time.Duration {
+	val, err := s.AsDuration()
+	if err != nil {
+		return def
+	}
+	return val
+}
+
+// AsTime 按照 layout 解析时间，layout 为空的时候使用 time.RFC3339
+func (s StringValue) AsTime(layout string) (time.Time, error) {
+	if s.Err != nil {
+		return time.Time{}, s.Err
+	}
+	if layout == "" {
+		layout = time.RFC3339
+	}
+	val, err := time.Parse(layout, s.Val)
+	return val, s.wrap(err)
+}
+
+func (s StringValue) AsTimeOr(layout string, def time.Time) time.Time {
+	val, err := s.AsTime(layout)
+	if err != nil {
+		return def
+	}
+	return val
+}
+
+// wrap 把转换失败的 error 转换成 *BindError，方便定位是哪个参数
+func (s StringValue) wrap(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &BindError{Field: s.Key, Value: s.Val, Err: err}
+}
+
+// PathValue 返回路径参数
+func (c *Context) PathValue(key string) StringValue {
+	val, ok := c.PathParams[key]
+	if !ok {
+		return StringValue{Key: key, Err: fmt.Errorf("%w: 路径参数 %s", ErrValueNotFound, key)}
+	}
+	return StringValue{Key: key, Val: val}
+}
+
+// QueryValue 返回查询参数，有多个值的时候返回第一个
+// 查询参数只会解析一次，解析结果缓存在 Context 上
+func (c *Context) QueryValue(key string) StringValue {
+	vals, ok := c.queryValues()[key]
+	if !ok || len(vals) == 0 {
+		return StringValue{Key: key, Err: fmt.Errorf("%w: 查询参数 %s", ErrValueNotFound, key)}
+	}
+	return StringValue{Key: key, Val: vals[0]}
+}
+
+// HeaderValue 返回请求头，有多个值的时候返回第一个
+func (c *Context) HeaderValue(key string) StringValue {
+	vals := c.Req.Header.Values(key)
+	if len(vals) == 0 {
+		return StringValue{Key: key, Err: fmt.Errorf("%w: 请求头 %s", ErrValueNotFound, key)}
+	}
+	return StringValue{Key: key, Val: vals[0]}
+}
+
+// queryValues 返回缓存的查询参数
+// 和 Req.URL.Query() 不同，这里不会每次都重新解析
+func (c *Context) queryValues() url.Values {
+	if c.queryCache == nil {
+		c.queryCache = c.Req.URL.Query()
+	}
+	return c.queryCache
+}
@@ -0,0 +1,57 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestContext_values(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/user/123?page=2&debug=true&timeout=1m30s&since=2022-08-01T00:00:00Z&page=3", nil)
+	req.Header.Set("X-Ratio", "0.5")
+	ctx := &Context{
+		Req:        req,
+		PathParams: map[string]string{"id": "123", "name": "tom"},
+	}
+
+	id, err := ctx.PathValue("id").AsInt64()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(123), id)
+	uid, err := ctx.PathValue("id").AsUint64()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(123), uid)
+
+	_, err = ctx.PathValue("name").AsInt64()
+	var bindErr *BindError
+	assert.ErrorAs(t, err, &bindErr)
+	assert.Equal(t, "name", bindErr.Field)
+	assert.Equal(t, int64(7), ctx.PathValue("name").AsInt64Or(7))
+
+	_, err = ctx.PathValue("missing").String()
+	assert.ErrorIs(t, err, ErrValueNotFound)
+	assert.Equal(t, "def", ctx.PathValue("missing").StringOr("def"))
+	assert.Equal(t, "tom", ctx.PathValue("name").StringOr("def"))
+
+	// 多个值的时候取第一个
+	assert.Equal(t, int64(2), ctx.QueryValue("page").AsInt64Or(1))
+	assert.True(t, ctx.QueryValue("debug").AsBoolOr(false))
+	assert.True(t, ctx.QueryValue("missing").AsBoolOr(true))
+	assert.Equal(t, 90*time.Second, ctx.QueryValue("timeout").AsDurationOr(0))
+	assert.Equal(t, time.Second, ctx.QueryValue("debug").AsDurationOr(time.Second))
+	since, err := ctx.QueryValue("since").AsTime("")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC), since)
+	assert.Equal(t, time.Time{}, ctx.QueryValue("debug").AsTimeOr("2006-01-02", time.Time{}))
+	_, err = ctx.QueryValue("missing").AsTime("")
+	assert.ErrorIs(t, err, ErrValueNotFound)
+
+	// 查询参数被缓存起来了
+	req.URL.RawQuery = "page=10"
+	assert.Equal(t, int64(2), ctx.QueryValue("page").AsInt64Or(1))
+
+	assert.Equal(t, 0.5, ctx.HeaderValue("x-ratio").AsFloat64Or(0))
+	assert.Equal(t, 1.5, ctx.HeaderValue("X-Missing").AsFloat64Or(1.5))
+	assert.Equal(t, uint64(8), ctx.HeaderValue("X-Missing").AsUint64Or(8))
+}
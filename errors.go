@@ -0,0 +1,139 @@
+package web
+
+import (
+	"errors"
+	"log"
+	"net/http"
+)
+
+// HandleErrFunc 是会返回 error 的 handler
+// 返回的 error 会交给 HTTPServer 的 ErrorHandler 转换成响应，参考 ServerWithErrorHandler
+type HandleErrFunc func(ctx *Context) error
+
+// ErrorHandler 把 HandleErrFunc 返回的 error 转换成响应
+type ErrorHandler func(ctx *Context, err error)
+
+// HTTPError 是带有响应码的 error
+// HandleErrFunc 返回 HTTPError 的时候，默认的 ErrorHandler 会用 Code 和 Message 作为响应
+type HTTPError struct {
+	// Code 响应码，0 当作 500 处理
+	Code    int
+	Message string
+	// Err 是引起这个错误的 error，不会返回给客户端
+	Err error
+}
+
+func NewHTTPError(code int, message string) *HTTPError {
+	return &HTTPError{Code: code, Message: message}
+}
+
+func (e *HTTPError) Error() string {
+	if e.Err != nil {
+		return e.message() + ": " + e.Err.Error()
+	}
+	return e.message()
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// status 返回响应码，没有设置 Code 的时候返回 500
+func (e *HTTPError) status() int {
+	if e.Code == 0 {
+		return http.StatusInternalServerError
+	}
+	return e.Code
+}
+
+// message 没有设置 Message 的时候使用响应码对应的描述
+func (e *HTTPError) message() string {
+	if e.Message == "" {
+		return http.StatusText(e.status())
+	}
+	return e.Message
+}
+
+// ServerWithErrorHandler 设置 ErrorHandler
+// 默认的 ErrorHandler 会把 HTTPError 和 *Problem 转换成对应的响应，ValidationErrors 转换成 422，
+// 其它 error 当作 500 处理；
+// 如果设置了 ServerWithInternalError，500 的时候会执行它
+func ServerWithErrorHandler(handler ErrorHandler) HTTPServerOption {
+	return func(server *HTTPServer) {
+		server.errorHandler = handler
+	}
+}
+
+// HandleErr 注册返回 error 的 handler
+func (s *HTTPServer) HandleErr(method string, path string, handler HandleErrFunc, opts ...RouteOption) {
+	s.addRoute(method, path, s.wrapErr(handler), opts...)
+}
+
+func (s *HTTPServer) GetErr(path string, handler HandleErrFunc, opts ...RouteOption) {
+	s.HandleErr(http.MethodGet, path, handler, opts...)
+}
+
+func (s *HTTPServer) PostErr(path string, handler HandleErrFunc, opts ...RouteOption) {
+	s.HandleErr(http.MethodPost, path, handler, opts...)
+}
+
+// wrapErr 把 HandleErrFunc 转换成 HandleFunc
+func (s *HTTPServer) wrapErr(handler HandleErrFunc) HandleFunc {
+	return func(ctx *Context) {
+		if err := handler(ctx); err != nil {
+			s.handleError(ctx, err)
+		}
+	}
+}
+
+func (s *HTTPServer) handleError(ctx *Context, err error) {
+	if s.errorHandler != nil {
+		s.errorHandler(ctx, err)
+		return
+	}
+	var p *Problem
+	if errors.As(err, &p) && ctx.Problem(p) == nil {
+		return
+	}
+	var validationErrs ValidationErrors
+	if errors.As(err, &validationErrs) {
+		ctx.ValidationFailed(validationErrs)
+		return
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		ctx.RespStatusCode = httpErr.status()
+		ctx.RespData = []byte(httpErr.message())
+		return
+	}
+	logError(ctx, err)
+	if s.internalError != nil {
+		s.internalError(ctx)
+		return
+	}
+	ctx.RespStatusCode = http.StatusInternalServerError
+	ctx.RespData = []byte(http.StatusText(http.StatusInternalServerError))
+}
+
+// JSONErrorHandler 以 {"code": 400, "message": "..."} 的形式返回 error
+// HTTPError 使用它的 Code 和 Message，ValidationErrors 返回 422，
+// 其它 error 返回 500，并且不会暴露 error 的内容
+func JSONErrorHandler(ctx *Context, err error) {
+	var validationErrs ValidationErrors
+	if errors.As(err, &validationErrs) {
+		ctx.ValidationFailed(validationErrs)
+		return
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		jsonError(ctx, httpErr.status(), httpErr.message())
+		return
+	}
+	logError(ctx, err)
+	JSONInternalError(ctx)
+}
+
+// logError 记录没有办法转换成具体响应的 error
+func logError(ctx *Context, err error) {
+	log.Printf("web: 处理请求 %s %s 出错，路由 %s: %v", ctx.Req.Method, ctx.Req.URL.Path, ctx.MatchedRoute, err)
+}
@@ -0,0 +1,118 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPServer_HandleErr(t *testing.T) {
+	testCases := []struct {
+		name     string
+		opts     []HTTPServerOption
+		err      error
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "no error",
+			wantCode: http.StatusOK,
+			wantBody: "ok",
+		},
+		{
+			name:     "http error",
+			err:      NewHTTPError(http.StatusBadRequest, "参数错误"),
+			wantCode: http.StatusBadRequest,
+			wantBody: "参数错误",
+		},
+		{
+			name:     "wrapped http error without message",
+			err:      fmt.Errorf("查询用户: %w", &HTTPError{Code: http.StatusForbidden}),
+			wantCode: http.StatusForbidden,
+			wantBody: "Forbidden",
+		},
+		{
+			name:     "http error without code",
+			err:      &HTTPError{Message: "bad"},
+			wantCode: http.StatusInternalServerError,
+			wantBody: "bad",
+		},
+		{
+			name:     "json http error without code",
+			opts:     []HTTPServerOption{ServerWithErrorHandler(JSONErrorHandler)},
+			err:      &HTTPError{},
+			wantCode: http.StatusInternalServerError,
+			wantBody: `{"code":500,"message":"Internal Server Error"}`,
+		},
+		{
+			name:     "problem http error without code",
+			opts:     []HTTPServerOption{ServerWithErrorHandler(ProblemErrorHandler)},
+			err:      &HTTPError{Message: "bad"},
+			wantCode: http.StatusInternalServerError,
+			wantBody: `{"detail":"bad","instance":"/user/123","route":"/user/:id","status":500,` +
+				`"title":"Internal Server Error","type":"about:blank"}`,
+		},
+		{
+			name:     "unknown error",
+			err:      errors.New("db down"),
+			wantCode: http.StatusInternalServerError,
+			wantBody: "Internal Server Error",
+		},
+		{
+			name:     "unknown error with internal error handler",
+			opts:     []HTTPServerOption{ServerWithInternalError(JSONInternalError)},
+			err:      errors.New("db down"),
+			wantCode: http.StatusInternalServerError,
+			wantBody: `{"code":500,"message":"Internal Server Error"}`,
+		},
+		{
+			name:     "json http error",
+			opts:     []HTTPServerOption{ServerWithErrorHandler(JSONErrorHandler)},
+			err:      NewHTTPError(http.StatusConflict, "用户已存在"),
+			wantCode: http.StatusConflict,
+			wantBody: `{"code":409,"message":"用户已存在"}`,
+		},
+		{
+			name:     "json unknown error",
+			opts:     []HTTPServerOption{ServerWithErrorHandler(JSONErrorHandler)},
+			err:      errors.New("db down"),
+			wantCode: http.StatusInternalServerError,
+			wantBody: `{"code":500,"message":"Internal Server Error"}`,
+		},
+		{
+			name: "custom error handler",
+			opts: []HTTPServerOption{ServerWithErrorHandler(func(ctx *Context, err error) {
+				ctx.RespStatusCode = http.StatusTeapot
+				ctx.RespData = []byte(err.Error())
+			})},
+			err:      errors.New("db down"),
+			wantCode: http.StatusTeapot,
+			wantBody: "db down",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewHTTPServer(tc.opts...)
+			s.GetErr("/user/:id", func(ctx *Context) error {
+				if tc.err != nil {
+					return tc.err
+				}
+				ctx.RespData = []byte("ok")
+				return nil
+			})
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/user/123", nil))
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+		})
+	}
+}
+
+func TestHTTPError_Error(t *testing.T) {
+	err := &HTTPError{Code: http.StatusNotFound, Err: errors.New("no rows")}
+	assert.Equal(t, "Not Found: no rows", err.Error())
+	assert.Equal(t, "参数错误", NewHTTPError(http.StatusBadRequest, "参数错误").Error())
+}
@@ -0,0 +1,154 @@
+package web
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Router 是和 HTTP 无关的通用路由，可以用来分发消息主题、命令行命令之类的东西
+// HTTPServer 的路由树也是同一套实现，所以语法和匹配顺序都是一样的：
+// 静态匹配，正则匹配 :name(reg)，参数匹配 :name，通配符匹配 *。
+// 区别在于分隔符是可以指定的，例如分隔符是 '.' 的时候可以注册 order.:id.created 和 order.*
+type Router[T any] struct {
+	sep    byte
+	root   *routeNode[T]
+	frozen *frozenTree[T]
+}
+
+// NewRouter 创建一个 Router，sep 是路由段之间的分隔符
+func NewRouter[T any](sep byte) *Router[T] {
+	return &Router[T]{
+		sep:  sep,
+		root: &routeNode[T]{path: string(sep)},
+	}
+}
+
+// Match 是 Router 的匹配结果
+type Match[T any] struct {
+	Handler T
+	// Pattern 命中的路由，例如 order.:id.created
+	Pattern string
+	// Params 命中的参数，例如 order.123.created 命中之后是 id => 123
+	Params map[string]string
+	Meta   *RouteMeta
+}
+
+// Add 注册路由
+// pattern 前后都不能有分隔符，中间也不能有连续的分隔符，空字符串代表根路由。
+// 正则表达式里面可以出现分隔符，例如分隔符是 '.' 的时候可以注册 :id(.+)，
+// 不过匹配的时候 path 还是先按照分隔符切分，正则表达式只会作用在其中一段上。
+// 冲突的规则和 HTTPServer 一样，非法的路由会 panic
+func (r *Router[T]) Add(pattern string, handler T, opts ...RouteOption) {
+	r.add(pattern, pattern, handler, opts)
+}
+
+// add 注册路由，HTTPServer 的路由也是通过它注册的
+// route 是完整的路由，用于报错和记录；pattern 是去掉了前缀之后要切分的部分，
+// 例如 HTTP 路由 /user/:id 的 pattern 是 user/:id
+func (r *Router[T]) add(route, pattern string, handler T, opts []RouteOption) {
+	if r.frozen != nil {
+		panic(fmt.Sprintf("web: 路由已经冻结，不能继续注册路由 [%s]", route))
+	}
+	if pattern == "" {
+		r.root.addSegs(route, nil, handler, opts)
+		return
+	}
+	if pattern[len(pattern)-1] == r.sep {
+		panic(fmt.Sprintf("web: 路由不能以 %c 结尾", r.sep))
+	}
+	segs, ok := splitPattern(pattern, r.sep)
+	if !ok {
+		panic(fmt.Sprintf("web: 非法路由，正则表达式的括号不匹配 [%s]", route))
+	}
+	for _, seg := range segs {
+		if seg == "" {
+			sep := string(r.sep)
+			panic(fmt.Sprintf("web: 非法路由。不允许使用 %s, %s 之类的路由, [%s]",
+				sep+sep+"a"+sep+"b", sep+"a"+sep+sep+"b", route))
+		}
+	}
+	r.root.addSegs(route, segs, handler, opts)
+}
+
+// Find 查找路由，path 前后的分隔符会被忽略
+// 没有命中注册了的路由的时候，第二个返回值是 false
+func (r *Router[T]) Find(path string) (Match[T], bool) {
+	mi, ok := r.find(strings.Trim(path, string(r.sep)))
+	if !ok || !mi.n.registered {
+		return Match[T]{}, false
+	}
+	return Match[T]{
+		Handler: mi.n.handler,
+		Pattern: mi.n.route,
+		Params:  mi.pathParams,
+		Meta:    mi.n.meta,
+	}, true
+}
+
+// find 查找 path，path 前后不能有分隔符，空字符串代表根节点
+// 返回的节点不一定注册了路由
+func (r *Router[T]) find(path string) (*routeMatch[T], bool) {
+	if r.frozen != nil {
+		return r.frozen.findPath(path)
+	}
+	return r.root.findPath(path, r.sep)
+}
+
+// findSegs 按照已经切分好的路由段查找，空的 segs 代表根节点
+func (r *Router[T]) findSegs(segs []string) (*routeMatch[T], bool) {
+	if r.frozen != nil {
+		return r.frozen.matchSegs(segs)
+	}
+	return r.root.matchSegs(segs)
+}
+
+// Freeze 冻结路由，参考 HTTPServer 的 Freeze
+func (r *Router[T]) Freeze() {
+	if r.frozen != nil {
+		return
+	}
+	r.frozen = newFrozenTree(r.root, r.sep)
+}
+
+// Routes 返回所有注册了的路由，按照路由排序
+func (r *Router[T]) Routes() []RouteInfo {
+	res := r.root.appendRoutes("", make([]RouteInfo, 0, 16))
+	sortRoutes(res)
+	return res
+}
+
+// splitPattern 按照 sep 切分路由，正则表达式括号里面的 sep 不会被切分
+// 只有 : 开头的路由段才会检查括号，括号不匹配的时候第二个返回值是 false。
+// router 和 Router 注册路由的时候都使用它
+func splitPattern(pattern string, sep byte) ([]string, bool) {
+	segs := make([]string, 0, strings.Count(pattern, string(sep))+1)
+	depth, start := 0, 0
+	for i := 0; i < len(pattern); i++ {
+		// 静态路由段里面的括号没有特殊含义
+		if pattern[start] != ':' {
+			if pattern[i] == sep {
+				segs = append(segs, pattern[start:i])
+				start = i + 1
+			}
+			continue
+		}
+		switch pattern[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return nil, false
+			}
+			depth--
+		case sep:
+			if depth == 0 {
+				segs = append(segs, pattern[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth > 0 {
+		return nil, false
+	}
+	return append(segs, pattern[start:]), true
+}
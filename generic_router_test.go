@@ -0,0 +1,94 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRouter(t *testing.T) {
+	patterns := []string{
+		"",
+		"order.created",
+		"order.:id.paid",
+		"order.:oid.refund.*",
+		"user.:uid([0-9]+)",
+		"member.:name(.+).login",
+		"log.*",
+	}
+	testCases := []struct {
+		name    string
+		path    string
+		found   bool
+		pattern string
+		params  map[string]string
+	}{
+		{name: "root", path: "", found: true, pattern: ""},
+		{name: "static", path: "order.created", found: true, pattern: "order.created"},
+		{name: "sep around", path: ".order.created.", found: true, pattern: "order.created"},
+		{name: "param", path: "order.123.paid", found: true, pattern: "order.:id.paid",
+			params: map[string]string{"id": "123"}},
+		{name: "param with star", path: "order.123.refund.a.b", found: true, pattern: "order.:oid.refund.*",
+			params: map[string]string{"oid": "123"}},
+		{name: "reg", path: "user.123", found: true, pattern: "user.:uid([0-9]+)",
+			params: map[string]string{"uid": "123"}},
+		{name: "reg with sep", path: "member.tom.login", found: true, pattern: "member.:name(.+).login",
+			params: map[string]string{"name": "tom"}},
+		{name: "star", path: "log.debug", found: true, pattern: "log.*"},
+		{name: "no handler", path: "order"},
+		{name: "not found", path: "order.123.unknown"},
+		{name: "empty seg", path: "order..created"},
+	}
+
+	for _, frozen := range []bool{false, true} {
+		r := NewRouter[int]('.')
+		for i, p := range patterns {
+			r.Add(p, i, RouteWithName(p))
+		}
+		if frozen {
+			r.Freeze()
+		}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				m, found := r.Find(tc.path)
+				assert.Equal(t, tc.found, found)
+				if !found {
+					return
+				}
+				assert.Equal(t, tc.pattern, m.Pattern)
+				assert.Equal(t, tc.pattern, patterns[m.Handler])
+				assert.Equal(t, tc.pattern, m.Meta.Name)
+				assert.Equal(t, tc.params, m.Params)
+			})
+		}
+	}
+}
+
+func TestRouter_Add(t *testing.T) {
+	r := NewRouter[string](' ')
+	r.Add("git commit", "commit")
+	assert.PanicsWithValue(t, "web: 路由冲突[git commit]", func() {
+		r.Add("git commit", "commit")
+	})
+	assert.PanicsWithValue(t, "web: 路由不能以   结尾", func() {
+		r.Add("git ", "git")
+	})
+	assert.PanicsWithValue(t, "web: 非法路由。不允许使用   a b,  a  b 之类的路由, [ git]", func() {
+		r.Add(" git", "git")
+	})
+	assert.PanicsWithValue(t, "web: 非法路由。不允许使用   a b,  a  b 之类的路由, [git  push]", func() {
+		r.Add("git  push", "push")
+	})
+	r.Add("git :sub", "sub")
+	m, ok := r.Find("git push")
+	assert.True(t, ok)
+	assert.Equal(t, "sub", m.Handler)
+	assert.Equal(t, []RouteInfo{
+		{Path: "git :sub", Meta: &RouteMeta{}},
+		{Path: "git commit", Meta: &RouteMeta{}},
+	}, r.Routes())
+
+	r.Freeze()
+	assert.PanicsWithValue(t, "web: 路由已经冻结，不能继续注册路由 [git pull]", func() {
+		r.Add("git pull", "pull")
+	})
+}
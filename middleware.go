@@ -0,0 +1,5 @@
+package web
+
+// Middleware 函数式的责任链模式
+// 也可以叫做洋葱模式
+type Middleware func(next HandleFunc) HandleFunc
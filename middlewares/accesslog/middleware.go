@@ -0,0 +1,154 @@
+package accesslog
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+	"web"
+)
+
+// Format 访问日志的格式
+type Format int
+
+const (
+	// FormatCommon Common Log Format
+	// 127.0.0.1 - tom [10/Oct/2000:13:55:36 -0700] "GET /user/123 HTTP/1.1" 200 2326
+	FormatCommon Format = iota
+	// FormatCombined Combined Log Format，在 Common Log Format 的基础上增加了 Referer 和 User-Agent
+	FormatCombined
+	// FormatJSON 一行一个 JSON，包含了所有的字段
+	FormatJSON
+)
+
+const clfTimeLayout = "02/Jan/2006:15:04:05 -0700"
+
+// Logger 输出一行访问日志，行末没有换行符
+type Logger interface {
+	Log(line string)
+}
+
+// LoggerFunc 让普通的方法实现 Logger
+type LoggerFunc func(line string)
+
+func (f LoggerFunc) Log(line string) {
+	f(line)
+}
+
+type MiddlewareBuilder struct {
+	format          Format
+	logger          Logger
+	requestIDHeader string
+}
+
+func NewMiddlewareBuilder() *MiddlewareBuilder {
+	return &MiddlewareBuilder{
+		format: FormatCommon,
+		logger: LoggerFunc(func(line string) {
+			log.Println(line)
+		}),
+		requestIDHeader: web.RequestIDHeader,
+	}
+}
+
+func (m *MiddlewareBuilder) Format(format Format) *MiddlewareBuilder {
+	m.format = format
+	return m
+}
+
+func (m *MiddlewareBuilder) Logger(logger Logger) *MiddlewareBuilder {
+	m.logger = logger
+	return m
+}
+
+// RequestIDHeader 从哪个请求头里面读取请求 ID，默认是 X-Request-Id
+func (m *MiddlewareBuilder) RequestIDHeader(header string) *MiddlewareBuilder {
+	m.requestIDHeader = header
+	return m
+}
+
+func (m *MiddlewareBuilder) Build() web.Middleware {
+	return func(next web.HandleFunc) web.HandleFunc {
+		return func(ctx *web.Context) {
+			start := time.Now()
+			resp := web.WrapResponseWriter(ctx.Resp)
+			ctx.Resp = resp
+			defer func() {
+				l := accessLog{
+					Time:      start,
+					Method:    ctx.Req.Method,
+					Route:     ctx.MatchedRoute,
+					Path:      ctx.Req.URL.Path,
+					URI:       ctx.Req.RequestURI,
+					Proto:     ctx.Req.Proto,
+					Status:    resp.Status(),
+					Bytes:     resp.Size() + int64(len(ctx.RespData)),
+					LatencyMs: float64(time.Since(start)) / float64(time.Millisecond),
+					ClientIP:  ctx.ClientIP(),
+					RequestID: ctx.Req.Header.Get(m.requestIDHeader),
+					Referer:   ctx.Req.Referer(),
+					UserAgent: ctx.Req.UserAgent(),
+				}
+				// 缓存的响应在所有的 middleware 执行完之后才会写入
+				if !resp.Written() && ctx.RespStatusCode > 0 {
+					l.Status = ctx.RespStatusCode
+				}
+				if l.URI == "" {
+					l.URI = ctx.Req.URL.RequestURI()
+				}
+				if user, _, ok := ctx.Req.BasicAuth(); ok {
+					l.User = user
+				}
+				m.logger.Log(l.format(m.format))
+			}()
+			next(ctx)
+		}
+	}
+}
+
+type accessLog struct {
+	Time      time.Time `json:"time"`
+	Method    string    `json:"method"`
+	Route     string    `json:"route,omitempty"`
+	Path      string    `json:"path"`
+	URI       string    `json:"-"`
+	Proto     string    `json:"proto"`
+	Status    int       `json:"status"`
+	Bytes     int64     `json:"bytes"`
+	LatencyMs float64   `json:"latency_ms"`
+	ClientIP  string    `json:"client_ip"`
+	RequestID string    `json:"request_id,omitempty"`
+	User      string    `json:"user,omitempty"`
+	Referer   string    `json:"referer,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+}
+
+func (l accessLog) format(format Format) string {
+	switch format {
+	case FormatJSON:
+		// 所有字段都能够被序列化，不会出错
+		data, _ := json.Marshal(l)
+		return string(data)
+	case FormatCombined:
+		return fmt.Sprintf("%s %s %s", l.common(), strconv.Quote(l.Referer), strconv.Quote(l.UserAgent))
+	default:
+		return l.common()
+	}
+}
+
+func (l accessLog) common() string {
+	bytes := "-"
+	if l.Bytes > 0 {
+		bytes = strconv.FormatInt(l.Bytes, 10)
+	}
+	return fmt.Sprintf("%s - %s [%s] %s %d %s", dash(l.ClientIP), dash(l.User),
+		l.Time.Format(clfTimeLayout), strconv.Quote(l.Method+" "+l.URI+" "+l.Proto), l.Status, bytes)
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
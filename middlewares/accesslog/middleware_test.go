@@ -0,0 +1,102 @@
+package accesslog
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"web"
+)
+
+func TestMiddlewareBuilder_Build(t *testing.T) {
+	testCases := []struct {
+		name     string
+		format   Format
+		wantLine *regexp.Regexp
+	}{
+		{
+			name:   "common",
+			format: FormatCommon,
+			wantLine: regexp.MustCompile(`^192\.0\.2\.1 - tom \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] ` +
+				`"GET /user/123\?a=b HTTP/1\.1" 201 5$`),
+		},
+		{
+			name:   "combined",
+			format: FormatCombined,
+			wantLine: regexp.MustCompile(`^192\.0\.2\.1 - tom \[.+\] "GET /user/123\?a=b HTTP/1\.1" 201 5 ` +
+				`"http://example\.com" "test-agent"$`),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			line := serve(t, NewMiddlewareBuilder().Format(tc.format))
+			assert.Regexp(t, tc.wantLine, line)
+		})
+	}
+}
+
+func TestMiddlewareBuilder_JSON(t *testing.T) {
+	line := serve(t, NewMiddlewareBuilder().Format(FormatJSON).RequestIDHeader("X-Trace-Id"))
+	var l map[string]any
+	require.NoError(t, json.Unmarshal([]byte(line), &l))
+	assert.Equal(t, "GET", l["method"])
+	assert.Equal(t, "/user/:id", l["route"])
+	assert.Equal(t, "/user/123", l["path"])
+	assert.Equal(t, float64(201), l["status"])
+	assert.Equal(t, float64(5), l["bytes"])
+	assert.Equal(t, "192.0.2.1", l["client_ip"])
+	assert.Equal(t, "trace-1", l["request_id"])
+	assert.Equal(t, "tom", l["user"])
+	assert.Contains(t, l, "latency_ms")
+}
+
+func serve(t *testing.T, builder *MiddlewareBuilder) string {
+	var line string
+	builder.Logger(LoggerFunc(func(l string) {
+		line = l
+	}))
+	s := web.NewHTTPServer(web.ServerWithMiddleware(builder.Build()))
+	s.Get("/user/:id", func(ctx *web.Context) {
+		ctx.Resp.WriteHeader(http.StatusCreated)
+		_, _ = ctx.Resp.Write([]byte("hello"))
+	})
+	req := httptest.NewRequest(http.MethodGet, "/user/123?a=b", nil)
+	req.SetBasicAuth("tom", "secret")
+	req.Header.Set("Referer", "http://example.com")
+	req.Header.Set("User-Agent", "test-agent")
+	req.Header.Set("X-Trace-Id", "trace-1")
+	s.ServeHTTP(httptest.NewRecorder(), req)
+	return line
+}
+
+func TestMiddlewareBuilder_bufferedResp(t *testing.T) {
+	var line string
+	builder := NewMiddlewareBuilder().Format(FormatJSON).Logger(LoggerFunc(func(l string) {
+		line = l
+	}))
+	s := web.NewHTTPServer(web.ServerWithMiddleware(builder.Build()))
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/unknown", nil))
+	var l map[string]any
+	require.NoError(t, json.Unmarshal([]byte(line), &l))
+	assert.Equal(t, float64(http.StatusNotFound), l["status"])
+	assert.Equal(t, float64(len("Not Found")), l["bytes"])
+}
+
+func TestMiddlewareBuilder_trustedProxies(t *testing.T) {
+	var line string
+	builder := NewMiddlewareBuilder().Format(FormatJSON).Logger(LoggerFunc(func(l string) {
+		line = l
+	}))
+	s := web.NewHTTPServer(web.ServerWithMiddleware(builder.Build()),
+		web.ServerWithTrustedProxies("192.0.2.0/24"))
+	s.Get("/", func(ctx *web.Context) {})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Forwarded-For", "203.0.113.5")
+	s.ServeHTTP(httptest.NewRecorder(), req)
+	var l map[string]any
+	require.NoError(t, json.Unmarshal([]byte(line), &l))
+	assert.Equal(t, "203.0.113.5", l["client_ip"])
+}
@@ -0,0 +1,73 @@
+package recovery
+
+import (
+	"log"
+	"net/http"
+	"runtime/debug"
+	"web"
+)
+
+// MiddlewareBuilder 构造捕获 panic 的 middleware
+// handler 里面的 panic 会被转换成 StatusCode 和 Data 组成的响应，
+// 而不是让 net/http 直接断开连接
+type MiddlewareBuilder struct {
+	// StatusCode 发生 panic 之后返回的响应码，默认是 500
+	StatusCode int
+	// Data 发生 panic 之后返回的响应
+	Data []byte
+	// Log 记录 panic 的值和调用栈，ctx.MatchedRoute 是命中的路由
+	Log func(ctx *web.Context, err any, stack []byte)
+	// RePanic 为 true 的时候，写完响应之后会用 http.ErrAbortHandler 重新 panic，
+	// net/http 会中断这个连接，但是不会再打印调用栈
+	RePanic bool
+}
+
+func NewMiddlewareBuilder() *MiddlewareBuilder {
+	return &MiddlewareBuilder{
+		StatusCode: http.StatusInternalServerError,
+		Data:       []byte(http.StatusText(http.StatusInternalServerError)),
+		Log: func(ctx *web.Context, err any, stack []byte) {
+			log.Printf("web: 处理请求 %s %s 发生 panic，路由 %s: %v\n%s",
+				ctx.Req.Method, ctx.Req.URL.Path, ctx.MatchedRoute, err, stack)
+		},
+	}
+}
+
+func (m *MiddlewareBuilder) Build() web.Middleware {
+	return func(next web.HandleFunc) web.HandleFunc {
+		return func(ctx *web.Context) {
+			defer func() {
+				err := recover()
+				if err == nil {
+					return
+				}
+				// 用户主动要求中断连接，不需要处理
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+				if m.Log != nil {
+					m.Log(ctx, err, debug.Stack())
+				}
+				// 响应头已经发送了的话，没办法再修改响应
+				resp := web.WrapResponseWriter(ctx.Resp)
+				if m.RePanic {
+					// 重新 panic 之后 HTTPServer 不会再写入缓存的响应，只能直接写
+					if !resp.Written() {
+						resp.WriteHeader(m.StatusCode)
+						_, _ = resp.Write(m.Data)
+					}
+					panic(http.ErrAbortHandler)
+				}
+				if resp.Written() {
+					ctx.RespStatusCode = 0
+					ctx.RespData = nil
+					return
+				}
+				// 覆盖 handler 缓存的响应，外层的 middleware 依旧可以修改
+				ctx.RespStatusCode = m.StatusCode
+				ctx.RespData = m.Data
+			}()
+			next(ctx)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package recovery
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"web"
+)
+
+func TestMiddlewareBuilder_Build(t *testing.T) {
+	var (
+		logRoute string
+		logErr   any
+	)
+	builder := NewMiddlewareBuilder()
+	builder.StatusCode = http.StatusTeapot
+	builder.Data = []byte("出错了")
+	builder.Log = func(ctx *web.Context, err any, stack []byte) {
+		logRoute = ctx.MatchedRoute
+		logErr = err
+	}
+	s := web.NewHTTPServer(web.ServerWithMiddleware(builder.Build()))
+	s.Get("/user/:id", func(ctx *web.Context) {
+		panic("boom")
+	})
+	s.Get("/abort", func(ctx *web.Context) {
+		panic(http.ErrAbortHandler)
+	})
+	s.Get("/written", func(ctx *web.Context) {
+		_, _ = ctx.Resp.Write([]byte("部分响应"))
+		panic("boom")
+	})
+	s.Get("/ok", func(ctx *web.Context) {
+		_, _ = ctx.Resp.Write([]byte("ok"))
+	})
+
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/user/123", nil))
+	assert.Equal(t, http.StatusTeapot, recorder.Code)
+	assert.Equal(t, "出错了", recorder.Body.String())
+	assert.Equal(t, "/user/:id", logRoute)
+	assert.Equal(t, "boom", logErr)
+
+	// 响应已经发送了，不能再修改
+	recorder = httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/written", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "部分响应", recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/ok", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "ok", recorder.Body.String())
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abort", nil))
+	})
+
+	builder.RePanic = true
+	s = web.NewHTTPServer(web.ServerWithMiddleware(builder.Build()))
+	s.Get("/user/:id", func(ctx *web.Context) {
+		panic("boom")
+	})
+	recorder = httptest.NewRecorder()
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/user/123", nil))
+	})
+	assert.Equal(t, http.StatusTeapot, recorder.Code)
+}
@@ -0,0 +1,123 @@
+package web
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// acceptRange 是 Accept 请求头里面的一项，例如 text/*;q=0.8
+type acceptRange struct {
+	typ     string
+	subtype string
+	q       float64
+}
+
+// parseAccept 解析 Accept 请求头，非法的项会被忽略
+func parseAccept(header string) []acceptRange {
+	var res []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		mediaType, params, _ := strings.Cut(part, ";")
+		typ, subtype, ok := strings.Cut(strings.ToLower(strings.TrimSpace(mediaType)), "/")
+		if !ok || typ == "" || subtype == "" || (typ == "*" && subtype != "*") {
+			continue
+		}
+		ar := acceptRange{typ: typ, subtype: subtype, q: 1}
+		for _, param := range strings.Split(params, ";") {
+			k, v, _ := strings.Cut(param, "=")
+			if strings.TrimSpace(strings.ToLower(k)) != "q" {
+				continue
+			}
+			q, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil || q < 0 || q > 1 {
+				q = 0
+			}
+			ar.q = q
+		}
+		res = append(res, ar)
+	}
+	return res
+}
+
+// match 返回 mediaType 和 ar 的匹配程度，-1 代表不匹配
+// 2 代表完全匹配，1 代表 type/* 匹配，0 代表 */* 匹配
+func (ar acceptRange) match(mediaType string) int {
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+	switch {
+	case ar.typ == "*":
+		return 0
+	case ar.typ != typ:
+		return -1
+	case ar.subtype == "*":
+		return 1
+	case ar.subtype == subtype:
+		return 2
+	default:
+		return -1
+	}
+}
+
+// quality 返回 mediaType 的权重，使用最具体的那一项的 q
+func quality(ranges []acceptRange, mediaType string) float64 {
+	q, best := 0.0, -1
+	for _, ar := range ranges {
+		if m := ar.match(mediaType); m > best {
+			q, best = ar.q, m
+		}
+	}
+	return q
+}
+
+// Negotiate 根据 Accept 请求头，从 offers 里面选择最合适的媒体类型，使用对应的 Codec 序列化 val
+// offers 为空的时候从所有注册了的 Codec 里面选择，按照媒体类型的字典序，例如 application/json 优先于 text/xml。
+// 没有对应 Codec 的 offer 会被忽略，q 相同的时候，排在前面的 offer 优先。
+// 没有 Accept 请求头的时候使用第一个 offer。
+// 没有合适的媒体类型的时候返回 406 的 *HTTPError，序列化失败的时候返回 error
+func (c *Context) Negotiate(code int, val any, offers ...string) error {
+	mediaType, ok := c.negotiate(offers)
+	if !ok {
+		return &HTTPError{Code: http.StatusNotAcceptable, Message: "没有可以接受的媒体类型"}
+	}
+	return c.Encode(code, mediaType, val)
+}
+
+func (c *Context) negotiate(offers []string) (string, bool) {
+	c.Resp.Header().Add("Vary", "Accept")
+	if len(offers) == 0 {
+		codecs := c.codecs
+		if codecs == nil {
+			codecs = defaultCodecs()
+		}
+		for mediaType := range codecs {
+			offers = append(offers, mediaType)
+		}
+		sort.Strings(offers)
+	}
+	candidates := make([]string, 0, len(offers))
+	for _, offer := range offers {
+		offer = strings.ToLower(offer)
+		if _, ok := c.codec(offer); ok {
+			candidates = append(candidates, offer)
+		}
+	}
+	if len(candidates) == 0 {
+		return "", false
+	}
+	header := strings.Join(c.Req.Header.Values("Accept"), ",")
+	if header == "" {
+		return candidates[0], true
+	}
+	ranges := parseAccept(header)
+	res, bestQ := "", 0.0
+	for _, offer := range candidates {
+		if q := quality(ranges, offer); q > bestQ {
+			res, bestQ = offer, q
+		}
+	}
+	return res, res != ""
+}
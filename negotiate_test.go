@@ -0,0 +1,135 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_parseAccept(t *testing.T) {
+	ranges := parseAccept("text/html, application/*;q=0.8 , */*; Q=0.1, invalid, */json, text/plain;q=abc")
+	assert.Equal(t, []acceptRange{
+		{typ: "text", subtype: "html", q: 1},
+		{typ: "application", subtype: "*", q: 0.8},
+		{typ: "*", subtype: "*", q: 0.1},
+		{typ: "text", subtype: "plain", q: 0},
+	}, ranges)
+}
+
+func TestContext_Negotiate(t *testing.T) {
+	type User struct {
+		Name string `json:"name" xml:"name"`
+	}
+	const (
+		jsonBody = `{"name":"Tom"}`
+		xmlBody  = "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<User><name>Tom</name></User>"
+	)
+	testCases := []struct {
+		name     string
+		accept   []string
+		offers   []string
+		wantCode int
+		wantType string
+		wantBody string
+	}{
+		{
+			name:     "no accept",
+			offers:   []string{"application/xml", "application/json"},
+			wantCode: http.StatusOK,
+			wantType: "application/xml",
+			wantBody: xmlBody,
+		},
+		{
+			name:     "no accept and no offers",
+			wantCode: http.StatusOK,
+			wantType: "application/json",
+			wantBody: jsonBody,
+		},
+		{
+			name:     "exact",
+			accept:   []string{"application/xml"},
+			wantCode: http.StatusOK,
+			wantType: "application/xml",
+			wantBody: xmlBody,
+		},
+		{
+			name:     "q value",
+			accept:   []string{"application/json;q=0.5, text/xml;q=0.9"},
+			wantCode: http.StatusOK,
+			wantType: "text/xml",
+			wantBody: xmlBody,
+		},
+		{
+			name:     "multiple headers",
+			accept:   []string{"application/json;q=0.5", "application/xml"},
+			wantCode: http.StatusOK,
+			wantType: "application/xml",
+			wantBody: xmlBody,
+		},
+		{
+			name:     "wildcard uses offer order",
+			accept:   []string{"*/*"},
+			offers:   []string{"text/xml", "application/json"},
+			wantCode: http.StatusOK,
+			wantType: "text/xml",
+			wantBody: xmlBody,
+		},
+		{
+			name:     "more specific wins",
+			accept:   []string{"application/*, application/json;q=0"},
+			offers:   []string{"application/json", "application/xml"},
+			wantCode: http.StatusOK,
+			wantType: "application/xml",
+			wantBody: xmlBody,
+		},
+		{
+			name:     "suffix",
+			accept:   []string{"application/problem+json"},
+			offers:   []string{"application/xml", "application/problem+json"},
+			wantCode: http.StatusOK,
+			wantType: "application/problem+json",
+			wantBody: jsonBody,
+		},
+		{
+			name:     "offer without codec",
+			accept:   []string{"application/msgpack, application/json;q=0.1"},
+			offers:   []string{"application/msgpack", "application/json"},
+			wantCode: http.StatusOK,
+			wantType: "application/json",
+			wantBody: jsonBody,
+		},
+		{
+			name:     "not acceptable",
+			accept:   []string{"text/html"},
+			wantCode: http.StatusNotAcceptable,
+			wantBody: "没有可以接受的媒体类型",
+		},
+		{
+			name:     "all rejected",
+			accept:   []string{"*/*;q=0"},
+			wantCode: http.StatusNotAcceptable,
+			wantBody: "没有可以接受的媒体类型",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewHTTPServer()
+			s.GetErr("/", func(ctx *Context) error {
+				return ctx.Negotiate(http.StatusOK, User{Name: "Tom"}, tc.offers...)
+			})
+			req, err := http.NewRequest(http.MethodGet, "/", nil)
+			require.NoError(t, err)
+			for _, accept := range tc.accept {
+				req.Header.Add("Accept", accept)
+			}
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantType, recorder.Header().Get("Content-Type"))
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+			assert.Equal(t, "Accept", recorder.Header().Get("Vary"))
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// ProblemContentType 是 RFC 7807 规定的 Content-Type
+const ProblemContentType = "application/problem+json"
+
+// RequestIDHeader 是携带请求 ID 的请求头
+const RequestIDHeader = "X-Request-Id"
+
+// Problem 是 RFC 7807 定义的错误响应
+// https://www.rfc-editor.org/rfc/rfc7807
+type Problem struct {
+	// Type 标识错误类型的 URI，为空的时候等价于 about:blank
+	Type string
+	// Title 错误类型的简短描述
+	Title string
+	// Status HTTP 响应码
+	Status int
+	// Detail 这一次错误的具体描述
+	Detail string
+	// Instance 标识这一次错误的 URI
+	Instance string
+	// Extensions 扩展字段，会和上面的字段平铺在同一个 JSON 对象里面
+	// 和标准字段同名的扩展字段会被忽略
+	Extensions map[string]any
+}
+
+// NewProblem 创建一个 Type 为 about:blank 的 Problem，Title 是响应码对应的描述
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// Error 让 *Problem 可以直接作为 HandleErrFunc 的返回值
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Title + ": " + p.Detail
+	}
+	return p.Title
+}
+
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	res := make(map[string]any, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		res[k] = v
+	}
+	setIfNotEmpty := func(key string, val string) {
+		if val != "" {
+			res[key] = val
+		} else {
+			delete(res, key)
+		}
+	}
+	setIfNotEmpty("type", p.Type)
+	setIfNotEmpty("title", p.Title)
+	setIfNotEmpty("detail", p.Detail)
+	setIfNotEmpty("instance", p.Instance)
+	if p.Status != 0 {
+		res["status"] = p.Status
+	} else {
+		delete(res, "status")
+	}
+	return json.Marshal(res)
+}
+
+// Problem 以 application/problem+json 的形式返回 p
+// 没有设置的时候，Status 默认是 500，Instance 默认是请求的路径；
+// 命中的路由和请求头 X-Request-Id 会分别作为扩展字段 route 和 request_id。
+// 这些默认值设置在 p 的副本上，所以 p 可以是多个请求共享的全局变量
+func (c *Context) Problem(problem *Problem) error {
+	p := *problem
+	if len(problem.Extensions) > 0 {
+		p.Extensions = make(map[string]any, len(problem.Extensions)+2)
+		for k, v := range problem.Extensions {
+			p.Extensions[k] = v
+		}
+	}
+	if p.Status == 0 {
+		p.Status = http.StatusInternalServerError
+	}
+	if p.Instance == "" {
+		p.Instance = c.Req.URL.Path
+	}
+	p.addExtension("route", c.MatchedRoute)
+	p.addExtension("request_id", c.Req.Header.Get(RequestIDHeader))
+	data, err := json.Marshal(&p)
+	if err != nil {
+		return err
+	}
+	c.Resp.Header().Set("Content-Type", ProblemContentType)
+	c.RespStatusCode = p.Status
+	c.RespData = data
+	return nil
+}
+
+// addExtension 在 val 不为空，并且没有同名扩展字段的时候设置扩展字段
+func (p *Problem) addExtension(key string, val string) {
+	if val == "" {
+		return
+	}
+	if _, ok := p.Extensions[key]; ok {
+		return
+	}
+	if p.Extensions == nil {
+		p.Extensions = make(map[string]any, 2)
+	}
+	p.Extensions[key] = val
+}
+
+// ServerWithProblemDetails 让 HTTPServer 自己产生的 404，405 和 500 响应都使用 application/problem+json
+// 相当于同时使用了 ServerWithNotFound(ProblemNotFound)，ServerWithMethodNotAllowed(ProblemMethodNotAllowed)，
+// ServerWithInternalError(ProblemInternalError) 和 ServerWithErrorHandler(ProblemErrorHandler)
+func ServerWithProblemDetails() HTTPServerOption {
+	return func(server *HTTPServer) {
+		server.notFound = ProblemNotFound
+		server.methodNotAllowed = ProblemMethodNotAllowed
+		server.internalError = ProblemInternalError
+		server.errorHandler = ProblemErrorHandler
+	}
+}
+
+// ProblemNotFound 以 application/problem+json 的形式返回 404
+func ProblemNotFound(ctx *Context) {
+	_ = ctx.Problem(NewProblem(http.StatusNotFound, ""))
+}
+
+// ProblemMethodNotAllowed 以 application/problem+json 的形式返回 405
+func ProblemMethodNotAllowed(ctx *Context) {
+	_ = ctx.Problem(NewProblem(http.StatusMethodNotAllowed, ""))
+}
+
+// ProblemInternalError 以 application/problem+json 的形式返回 500
+func ProblemInternalError(ctx *Context) {
+	_ = ctx.Problem(NewProblem(http.StatusInternalServerError, ""))
+}
+
+// ProblemErrorHandler 以 application/problem+json 的形式返回 error
+// *Problem 会被原样返回，HTTPError 的 Message 作为 Detail，
+// ValidationErrors 返回 422，并且放在扩展字段 errors 里面，
+// 其它 error 返回 500，并且不会暴露 error 的内容
+func ProblemErrorHandler(ctx *Context, err error) {
+	var p *Problem
+	if errors.As(err, &p) && ctx.Problem(p) == nil {
+		return
+	}
+	var validationErrs ValidationErrors
+	if errors.As(err, &validationErrs) {
+		p = NewProblem(http.StatusUnprocessableEntity, "请求参数没有通过校验")
+		p.Extensions = map[string]any{"errors": validationErrs}
+		_ = ctx.Problem(p)
+		return
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		_ = ctx.Problem(NewProblem(httpErr.status(), httpErr.message()))
+		return
+	}
+	logError(ctx, err)
+	ProblemInternalError(ctx)
+}
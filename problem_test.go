@@ -0,0 +1,152 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestProblem_MarshalJSON(t *testing.T) {
+	p := NewProblem(http.StatusBadRequest, "余额不足")
+	p.Type = "https://example.com/probs/out-of-credit"
+	p.Extensions = map[string]any{
+		"balance": 30,
+		// 和标准字段同名，会被忽略
+		"status": 200,
+	}
+	data, err := json.Marshal(p)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type":"https://example.com/probs/out-of-credit","title":"Bad Request",`+
+		`"status":400,"detail":"余额不足","balance":30}`, string(data))
+
+	_, err = json.Marshal(&Problem{Extensions: map[string]any{"ch": make(chan int)}})
+	assert.Error(t, err)
+}
+
+func TestServerWithProblemDetails(t *testing.T) {
+	testCases := []struct {
+		name     string
+		method   string
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "not found",
+			method:   http.MethodGet,
+			path:     "/abc",
+			wantCode: http.StatusNotFound,
+			wantBody: `{"type":"about:blank","title":"Not Found","status":404,"instance":"/abc","request_id":"req-1"}`,
+		},
+		{
+			name:     "method not allowed",
+			method:   http.MethodPost,
+			path:     "/user/123",
+			wantCode: http.StatusMethodNotAllowed,
+			wantBody: `{"type":"about:blank","title":"Method Not Allowed","status":405,"instance":"/user/123",` +
+				`"request_id":"req-1"}`,
+		},
+		{
+			name:     "panic",
+			method:   http.MethodGet,
+			path:     "/panic",
+			wantCode: http.StatusInternalServerError,
+			wantBody: `{"type":"about:blank","title":"Internal Server Error","status":500,"instance":"/panic",` +
+				`"route":"/panic","request_id":"req-1"}`,
+		},
+		{
+			name:     "http error",
+			method:   http.MethodGet,
+			path:     "/user/123",
+			wantCode: http.StatusForbidden,
+			wantBody: `{"type":"about:blank","title":"Forbidden","status":403,"detail":"没有权限",` +
+				`"instance":"/user/123","route":"/user/:id","request_id":"req-1"}`,
+		},
+		{
+			name:     "problem",
+			method:   http.MethodGet,
+			path:     "/order/123",
+			wantCode: http.StatusConflict,
+			wantBody: `{"type":"https://example.com/probs/paid","title":"订单已支付","status":409,` +
+				`"instance":"/order/123","route":"/order/:id","request_id":"req-1"}`,
+		},
+		{
+			name:     "unknown error",
+			method:   http.MethodGet,
+			path:     "/error",
+			wantCode: http.StatusInternalServerError,
+			wantBody: `{"type":"about:blank","title":"Internal Server Error","status":500,"instance":"/error",` +
+				`"route":"/error","request_id":"req-1"}`,
+		},
+	}
+	s := NewHTTPServer(ServerWithProblemDetails())
+	s.GetErr("/user/:id", func(ctx *Context) error {
+		return NewHTTPError(http.StatusForbidden, "没有权限")
+	})
+	s.GetErr("/order/:id", func(ctx *Context) error {
+		return &Problem{Type: "https://example.com/probs/paid", Title: "订单已支付", Status: http.StatusConflict}
+	})
+	s.GetErr("/error", func(ctx *Context) error {
+		return errors.New("db down")
+	})
+	s.Get("/panic", func(ctx *Context) {
+		panic("boom")
+	})
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			req.Header.Set(RequestIDHeader, "req-1")
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, req)
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, ProblemContentType, recorder.Header().Get("Content-Type"))
+			assert.JSONEq(t, tc.wantBody, recorder.Body.String())
+		})
+	}
+}
+
+func TestContext_Problem_shared(t *testing.T) {
+	// 全局共享的 Problem 不会被请求修改
+	errPaid := &Problem{
+		Type:       "https://example.com/probs/paid",
+		Title:      "订单已支付",
+		Status:     http.StatusConflict,
+		Extensions: map[string]any{"retry": false},
+	}
+	s := NewHTTPServer(ServerWithProblemDetails())
+	s.GetErr("/order/:id", func(ctx *Context) error {
+		return errPaid
+	})
+	for _, id := range []string{"1", "2"} {
+		req := httptest.NewRequest(http.MethodGet, "/order/"+id, nil)
+		req.Header.Set(RequestIDHeader, "req-"+id)
+		recorder := httptest.NewRecorder()
+		s.ServeHTTP(recorder, req)
+		assert.Equal(t, http.StatusConflict, recorder.Code)
+		assert.JSONEq(t, `{"type":"https://example.com/probs/paid","title":"订单已支付","status":409,`+
+			`"instance":"/order/`+id+`","route":"/order/:id","request_id":"req-`+id+`","retry":false}`,
+			recorder.Body.String())
+	}
+	assert.Equal(t, &Problem{
+		Type:       "https://example.com/probs/paid",
+		Title:      "订单已支付",
+		Status:     http.StatusConflict,
+		Extensions: map[string]any{"retry": false},
+	}, errPaid)
+
+	// 并发使用同一个 Problem
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/order/3", nil))
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,161 @@
+package web
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+)
+
+// ResponseWriter 封装了 http.ResponseWriter，记录响应码，写入的字节数，以及响应头是否已经发送
+// HTTPServer 交给 middleware 和 handler 的 Context.Resp 都是 ResponseWriter，
+// 所以 middleware 在 next 返回之后可以通过 WrapResponseWriter(ctx.Resp) 拿到这些数据。
+// 如果原本的 http.ResponseWriter 实现了 http.Flusher，http.Hijacker 或者 io.ReaderFrom，
+// 封装之后的 ResponseWriter 也会实现对应的接口
+type ResponseWriter interface {
+	http.ResponseWriter
+	// Status 响应码，没有调用过 WriteHeader 的时候是 200
+	Status() int
+	// Size 已经写入的响应体的字节数
+	Size() int64
+	// Written 响应头是否已经发送
+	Written() bool
+	// Unwrap 返回被封装的 http.ResponseWriter
+	Unwrap() http.ResponseWriter
+}
+
+// WrapResponseWriter 封装 w
+// w 已经是 ResponseWriter 的时候直接返回 w
+func WrapResponseWriter(w http.ResponseWriter) ResponseWriter {
+	if rw, ok := w.(ResponseWriter); ok {
+		return rw
+	}
+	rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+	_, isFlusher := w.(http.Flusher)
+	_, isHijacker := w.(http.Hijacker)
+	_, isReaderFrom := w.(io.ReaderFrom)
+	// 嵌入哪几个接口，返回的类型就实现哪几个接口
+	switch {
+	case isFlusher && isHijacker && isReaderFrom:
+		return struct {
+			*responseWriter
+			flusher
+			hijacker
+			readerFrom
+		}{rw, flusher{rw}, hijacker{rw}, readerFrom{rw}}
+	case isFlusher && isHijacker:
+		return struct {
+			*responseWriter
+			flusher
+			hijacker
+		}{rw, flusher{rw}, hijacker{rw}}
+	case isFlusher && isReaderFrom:
+		return struct {
+			*responseWriter
+			flusher
+			readerFrom
+		}{rw, flusher{rw}, readerFrom{rw}}
+	case isHijacker && isReaderFrom:
+		return struct {
+			*responseWriter
+			hijacker
+			readerFrom
+		}{rw, hijacker{rw}, readerFrom{rw}}
+	case isFlusher:
+		return struct {
+			*responseWriter
+			flusher
+		}{rw, flusher{rw}}
+	case isHijacker:
+		return struct {
+			*responseWriter
+			hijacker
+		}{rw, hijacker{rw}}
+	case isReaderFrom:
+		return struct {
+			*responseWriter
+			readerFrom
+		}{rw, readerFrom{rw}}
+	default:
+		return rw
+	}
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	status  int
+	size    int64
+	written bool
+}
+
+// WriteHeader 只有第一次调用会生效，后面的调用会被忽略
+// 1xx 的响应码除外，它们可以在最终的响应之前发送多次
+func (w *responseWriter) WriteHeader(statusCode int) {
+	if w.written {
+		return
+	}
+	if statusCode >= 100 && statusCode < 200 && statusCode != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	w.status = statusCode
+	w.written = true
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *responseWriter) Write(data []byte) (int, error) {
+	w.written = true
+	n, err := w.ResponseWriter.Write(data)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *responseWriter) Status() int {
+	return w.status
+}
+
+func (w *responseWriter) Size() int64 {
+	return w.size
+}
+
+func (w *responseWriter) Written() bool {
+	return w.written
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+type flusher struct {
+	w *responseWriter
+}
+
+func (f flusher) Flush() {
+	// Flush 会发送响应头
+	f.w.written = true
+	f.w.ResponseWriter.(http.Flusher).Flush()
+}
+
+type hijacker struct {
+	w *responseWriter
+}
+
+func (h hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, buf, err := h.w.ResponseWriter.(http.Hijacker).Hijack()
+	if err == nil {
+		// 连接已经被接管，不能再写入响应
+		h.w.written = true
+	}
+	return conn, buf, err
+}
+
+type readerFrom struct {
+	w *responseWriter
+}
+
+func (r readerFrom) ReadFrom(src io.Reader) (int64, error) {
+	r.w.written = true
+	n, err := r.w.ResponseWriter.(io.ReaderFrom).ReadFrom(src)
+	r.w.size += n
+	return n, err
+}
@@ -0,0 +1,124 @@
+package web
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWrapResponseWriter(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := WrapResponseWriter(recorder)
+	assert.Equal(t, w, WrapResponseWriter(w))
+	assert.Equal(t, http.StatusOK, w.Status())
+	assert.False(t, w.Written())
+
+	w.WriteHeader(http.StatusCreated)
+	// 重复调用会被忽略
+	w.WriteHeader(http.StatusBadRequest)
+	n, err := w.Write([]byte("hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, 5, n)
+	assert.True(t, w.Written())
+	assert.Equal(t, http.StatusCreated, w.Status())
+	assert.Equal(t, int64(5), w.Size())
+	assert.Equal(t, http.StatusCreated, recorder.Code)
+	assert.Same(t, recorder, w.Unwrap())
+
+	// httptest.ResponseRecorder 只实现了 http.Flusher
+	_, ok := w.(http.Flusher)
+	assert.True(t, ok)
+	_, ok = w.(http.Hijacker)
+	assert.False(t, ok)
+	_, ok = w.(io.ReaderFrom)
+	assert.False(t, ok)
+	w.(http.Flusher).Flush()
+	assert.True(t, recorder.Flushed)
+}
+
+func TestWrapResponseWriter_interfaces(t *testing.T) {
+	testCases := []struct {
+		name           string
+		w              http.ResponseWriter
+		wantFlusher    bool
+		wantHijacker   bool
+		wantReaderFrom bool
+	}{
+		{name: "plain", w: &plainWriter{}},
+		{name: "hijacker", w: &hijackWriter{}, wantHijacker: true},
+		{name: "reader from", w: &readFromWriter{}, wantReaderFrom: true},
+		{name: "all", w: &fullWriter{}, wantFlusher: true, wantHijacker: true, wantReaderFrom: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := WrapResponseWriter(tc.w)
+			_, ok := w.(http.Flusher)
+			assert.Equal(t, tc.wantFlusher, ok)
+			_, ok = w.(http.Hijacker)
+			assert.Equal(t, tc.wantHijacker, ok)
+			_, ok = w.(io.ReaderFrom)
+			assert.Equal(t, tc.wantReaderFrom, ok)
+		})
+	}
+
+	// 1xx 不算发送了响应头
+	w := WrapResponseWriter(&plainWriter{})
+	w.WriteHeader(http.StatusEarlyHints)
+	assert.False(t, w.Written())
+	assert.Equal(t, http.StatusOK, w.Status())
+
+	w = WrapResponseWriter(&fullWriter{})
+	n, err := w.(io.ReaderFrom).ReadFrom(strings.NewReader("hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), n)
+	assert.Equal(t, int64(5), w.Size())
+	assert.True(t, w.Written())
+	_, _, err = w.(http.Hijacker).Hijack()
+	assert.Equal(t, http.ErrHijacked, err)
+}
+
+type plainWriter struct {
+	buf bytes.Buffer
+}
+
+func (w *plainWriter) Write(data []byte) (int, error) {
+	return w.buf.Write(data)
+}
+
+func (w *plainWriter) Header() http.Header {
+	return http.Header{}
+}
+
+func (w *plainWriter) WriteHeader(statusCode int) {}
+
+type hijackWriter struct {
+	plainWriter
+}
+
+func (w *hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, http.ErrHijacked
+}
+
+type readFromWriter struct {
+	plainWriter
+}
+
+func (w *readFromWriter) ReadFrom(src io.Reader) (int64, error) {
+	return w.buf.ReadFrom(src)
+}
+
+type fullWriter struct {
+	hijackWriter
+}
+
+func (w *fullWriter) ReadFrom(src io.Reader) (int64, error) {
+	return w.buf.ReadFrom(src)
+}
+
+func (w *fullWriter) Flush() {}
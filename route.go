@@ -2,6 +2,7 @@ package web
 
 import (
 	"fmt"
+	"net/url"
 	"reflect"
 	"regexp"
 	"runtime"
@@ -10,13 +11,16 @@ import (
 
 type router struct {
 	// trees 是按照 HTTP 方法来组织的
-	// 如 GET => *node
-	trees map[string]*node
+	// 如 GET => *Router[HandleFunc]，分隔符是 /
+	trees map[string]*Router[HandleFunc]
+
+	// frozen 调用了 Freeze 之后为 true，不能继续注册路由
+	frozen bool
 }
 
 func newRouter() router {
 	return router{
-		trees: map[string]*node{},
+		trees: map[string]*Router[HandleFunc]{},
 	}
 }
 
@@ -24,10 +28,15 @@ func newRouter() router {
 // method 是 HTTP 方法
 // - 已经注册了的路由，无法被覆盖。例如 /user/home 注册两次，会冲突[already given]
 // - path 必须以 / 开始并且结尾不能有 /，中间也不允许有连续的 / [already given]
-// - 不能在同一个位置注册不同的参数路由，例如 /user/:id 和 /user/:name 冲突 [already given]
+// - 同一个位置允许注册不同名字的参数路由，例如 /user/:id/posts 和 /user/:name/profile，匹配时按各自的参数名绑定
+// - 同一个位置的正则路由，正则表达式必须相同，参数名可以不同
 // - 不能在同一个位置同时注册通配符路由和参数路由，例如 /user/:id 和 /user/* 冲突 [already given]
 // - 同名路径参数，在路由匹配的时候，值会被覆盖。例如 /user/:id/abc/:id，那么 /user/123/abc/456 最终 id = 456
-func (r *router) addRoute(method string, path string, handler HandleFunc) {
+// opts 是路由的元数据，参考 RouteMeta
+func (r *router) addRoute(method string, path string, handler HandleFunc, opts ...RouteOption) {
+	if r.frozen {
+		panic(fmt.Sprintf("web: 路由已经冻结，不能继续注册路由 [%s]", path))
+	}
 	//避免空路由
 	if path == "" {
 		panic("web: 路由是空字符串")
@@ -36,82 +45,159 @@ func (r *router) addRoute(method string, path string, handler HandleFunc) {
 	if path[0] != '/' {
 		panic("web: 路由必须以 / 开头")
 	}
-	//避免路由地址由/结尾
-	if path != "/" && path[len(path)-1] == '/' {
-		panic("web: 路由不能以 / 结尾")
-	}
 
-	root, ok := r.trees[method]
-	// 这是一个全新的 HTTP 方法，创建根节点
+	tree, ok := r.trees[method]
+	// 这是一个全新的 HTTP 方法，创建路由树
 	if !ok {
-		// 创建根节点
-		root = &node{path: "/"}
-		r.trees[method] = root
-	}
-	//判断是否为根结点路由，如果未注册句柄过则注册句柄
-	if path == "/" {
-		if root.handler != nil {
-			panic("web: 路由冲突[/]")
-		}
-		root.handler = handler
-		return
+		tree = NewRouter[HandleFunc]('/')
+		r.trees[method] = tree
 	}
+	// 去除第一个 /，剩下的校验和切分都和 Router 一样，正则表达式括号里面的 / 不会被切分
+	tree.add(path, path[1:], handler, opts)
+}
 
-	//去除第一个/，并且切分
-	segs := strings.Split(path[1:], "/") //有空格行么？有特殊字符行么
+// addSegs 从 n 开始一段段查找或者创建节点，并且在最后一个节点上注册路由
+// route 是完整的路由，用于报错和记录
+func (n *routeNode[T]) addSegs(route string, segs []string, handler T, opts []RouteOption) {
+	root := n
+	// 记录这条路由自己的参数名，同一个位置的参数节点可能被不同名字的路由共享
+	var paramNames []string
 	// 开始一段段处理
 	for _, s := range segs {
-		if s == "" {
-			panic(fmt.Sprintf("web: 非法路由。不允许使用 //a/b, /a//b 之类的路由, [%s]", path))
-		}
 		root = root.childOrCreate(s)
+		if root.typ == nodeTypeParam || root.typ == nodeTypeReg {
+			paramNames = append(paramNames, segParamName(s))
+		}
 	}
 	//如果已经注册过句柄，则需要panic
-	if root.handler != nil {
-		panic(fmt.Sprintf("web: 路由冲突[%s]", path))
+	if root.registered {
+		panic(fmt.Sprintf("web: 路由冲突[%s]", route))
 	}
-	//如果错误注册了路由，应该使用什么接口修改？无法重新写入吧？
-	root.handler = handler
+	root.setHandler(route, handler, paramNames, opts)
+}
+
+// setHandler 在节点上注册路由
+func (n *routeNode[T]) setHandler(route string, handler T, paramNames []string, opts []RouteOption) {
+	meta := &RouteMeta{}
+	for _, opt := range opts {
+		opt(meta)
+	}
+	n.handler = handler
+	n.registered = true
+	n.route = route
+	n.paramNames = paramNames
+	n.meta = meta
+}
+
+// segParamName 从参数路由段或者正则路由段里面取出参数名
+// 例如 :id 返回 id，:id(^[0-9]+$) 也返回 id
+func segParamName(seg string) string {
+	if idx := strings.Index(seg, "("); idx > 0 {
+		return seg[1:idx]
+	}
+	return seg[1:]
 }
 
 // findRoute 查找对应的节点
 // 注意，返回的 node 内部 HandleFunc 不为 nil 才算是注册了路由 //难道不是"才算是找到了路由"？
 func (r *router) findRoute(method string, path string) (*matchInfo, bool) {
-	root, ok := r.trees[method]
+	tree, ok := r.trees[method]
 	if !ok {
 		return nil, false
 	}
+	return tree.find(strings.Trim(path, "/"))
+}
 
-	if path == "/" {
-		return &matchInfo{n: root}, true //root.handler != nil
+// findEscapedRoute 按照转义之后的路径查找路由
+// 只按照真正的 / 切分 path，切分之后每一段再反转义，
+// 所以参数值里面的 %2F 不会被当成分隔符，命中的参数值也都是反转义之后的值。
+// path 里面有非法的转义序列的时候返回 error
+func (r *router) findEscapedRoute(method string, path string) (*matchInfo, bool, error) {
+	tree, ok := r.trees[method]
+	if !ok {
+		return nil, false, nil
 	}
 
-	segs := strings.Split(strings.Trim(path, "/"), "/")
-	mi := &matchInfo{}
+	path = strings.Trim(path, "/")
+	if path == "" {
+		mi, ok := tree.find(path)
+		return mi, ok, nil
+	}
+	segs := strings.Split(path, "/")
+	for i, seg := range segs {
+		val, err := url.PathUnescape(seg)
+		if err != nil {
+			return nil, false, fmt.Errorf("web: 非法的路径转义 %s: %w", seg, err)
+		}
+		segs[i] = val
+	}
+	mi, ok := tree.findSegs(segs)
+	return mi, ok, nil
+}
+
+// findPath 从 n 开始查找 path，path 前后不能有 sep，空字符串代表 n 自己
+// Router 没有冻结的时候通过它查找
+func (n *routeNode[T]) findPath(path string, sep byte) (*routeMatch[T], bool) {
+	if path == "" {
+		return &routeMatch[T]{n: n}, true //n.handler != nil
+	}
+	return n.matchSegs(strings.Split(path, string(sep)))
+}
+
+// matchSegs 从 n 开始，一段段匹配 segs
+func (n *routeNode[T]) matchSegs(segs []string) (*routeMatch[T], bool) {
+	root := n
+	var ok bool
+	mi := &routeMatch[T]{}
+	// 按顺序记录命中参数路由和正则路由的值，最后按照命中路由的参数名绑定
+	var values []string
 	for _, s := range segs {
 		var matchParam, matchRegex bool
 		root, matchParam, matchRegex, ok = root.childOf(s)
 		if !ok {
 			return nil, false
 		}
-		//命中参数路由
-		if matchParam {
-			mi.addValue(root.path[1:], s)
-		}
-		if matchRegex {
-			mi.addValue(root.path[1:strings.Index(root.path, "(")], s)
+		//命中参数路由或者正则路由
+		if matchParam || matchRegex {
+			mi.addValue(root.paramName, s)
+			values = append(values, s)
 		}
 		if !(matchParam || matchRegex) && root.typ == nodeTypeAny && root.children == nil && root.regChild == nil && root.paramChild == nil && root.starChild == nil {
-			mi.n = root
-			return mi, true
+			break
 		}
 	}
 	mi.n = root
+	mi.bindParamNames(values)
 	return mi, true //root.handler != nil
 }
 
 type nodeType int
 
+// RouteType 是路由节点的类型
+type RouteType int
+
+const (
+	RouteTypeStatic RouteType = nodeTypeStatic
+	RouteTypeReg    RouteType = nodeTypeReg
+	RouteTypeParam  RouteType = nodeTypeParam
+	RouteTypeAny    RouteType = nodeTypeAny
+)
+
+func (t RouteType) String() string {
+	switch t {
+	case RouteTypeStatic:
+		return "static"
+	case RouteTypeReg:
+		return "regexp"
+	case RouteTypeParam:
+		return "param"
+	case RouteTypeAny:
+		return "any"
+	default:
+		return fmt.Sprintf("RouteType(%d)", int(t))
+	}
+}
+
 const (
 	// 静态路由
 	nodeTypeStatic = iota
@@ -123,31 +209,45 @@ const (
 	nodeTypeAny
 )
 
-// node 代表路由树的节点
+// node 是 HTTP 路由树的节点
+type node = routeNode[HandleFunc]
+
+// routeNode 代表路由树的节点，T 是命中之后的处理逻辑
 // 路由树的匹配顺序是：
 // 1. 静态完全匹配
 // 2. 路径参数匹配：形式 :param_name
 // 3. 通配符匹配：*
 // 这是不回溯匹配
-type node struct {
+type routeNode[T any] struct {
 	typ nodeType
 
 	path string
 	// children 子节点
 	// 子节点的 path => node
-	children map[string]*node
+	children map[string]*routeNode[T]
 	// handler 命中路由之后执行的逻辑
-	handler HandleFunc
+	handler T
+	// registered 代表这个节点上注册了路由
+	registered bool
+	// route 注册时候的完整路由，例如 /user/:id
+	// 只有注册了 handler 的节点才会有
+	route string
+	// meta 注册路由时候附加的元数据
+	meta *RouteMeta
 
 	// 通配符 * 表达的节点，任意匹配
-	starChild *node
+	starChild *routeNode[T]
 
-	paramChild *node
+	paramChild *routeNode[T]
 	// 正则路由和参数路由都会使用这个字段
+	// 同一个位置被不同名字的路由共享的时候，这里是第一次注册的名字
 	paramName string
+	// paramNames 注册在这个节点上的路由，按照顺序出现的参数名
+	// 只有注册了 handler 的节点才会有
+	paramNames []string
 
 	// 正则表达式
-	regChild *node
+	regChild *routeNode[T]
 	regExpr  *regexp.Regexp
 }
 
@@ -156,7 +256,7 @@ type node struct {
 // 其次判断 path 是不是参数路径，即以 : 开头的路径
 // 最后会从 children 里面查找，
 // 如果没有找到，那么会创建一个新的节点，并且保存在 node 里面
-func (n *node) childOrCreate(path string) *node {
+func (n *routeNode[T]) childOrCreate(path string) *routeNode[T] {
 	if path == "*" {
 		if n.paramChild != nil {
 			panic(fmt.Sprintf("web: 非法路由，已有路径参数路由。不允许同时注册通配符路由和参数路由 [%s]", path))
@@ -165,7 +265,7 @@ func (n *node) childOrCreate(path string) *node {
 			panic(fmt.Sprintf("web: 非法路由，已有正则路由。不允许同时注册通配符路由和正则路由 [%s]", path))
 		}
 		if n.starChild == nil {
-			n.starChild = &node{
+			n.starChild = &routeNode[T]{
 				path: path,
 				typ:  nodeTypeAny,
 			}
@@ -180,16 +280,18 @@ func (n *node) childOrCreate(path string) *node {
 		if n.paramChild != nil {
 			panic(fmt.Sprintf("web: 非法路由，已有路径参数路由。不允许同时注册正则路由和参数路由 [%s]", path))
 		}
+		markIndex := strings.Index(path, "(")
 		if n.regChild != nil {
-			if n.regChild.path != path {
+			// 参数名可以不同，但是正则表达式必须一样
+			oldPath := n.regChild.path
+			if oldPath[strings.Index(oldPath, "("):] != path[markIndex:] {
 				panic(fmt.Sprintf("web: 路由冲突，正则路由冲突，已有 %s，新注册 %s", n.regChild.path, path))
 			}
 		} else {
-			markIndex := strings.Index(path, "(")
 			if string(path[markIndex+1]) == ")" {
 				panic(fmt.Sprintf("web: 正则路由的正则规则不能为空"))
 			}
-			n.regChild = &node{
+			n.regChild = &routeNode[T]{
 				path:      path,
 				typ:       nodeTypeReg,
 				paramName: path[1:markIndex],
@@ -207,12 +309,10 @@ func (n *node) childOrCreate(path string) *node {
 		if n.regChild != nil {
 			panic(fmt.Sprintf("web: 非法路由，已有正则路由。不允许同时注册正则路由和参数路由 [%s]", path))
 		}
-		if n.paramChild != nil {
-			if n.paramChild.path != path {
-				panic(fmt.Sprintf("web: 路由冲突，参数路由冲突，已有 %s，新注册 %s", n.paramChild.path, path))
-			}
-		} else {
-			n.paramChild = &node{
+		// 已经有参数路由的时候，不管名字是否相同都复用这个节点，
+		// 参数名由命中的路由自己决定
+		if n.paramChild == nil {
+			n.paramChild = &routeNode[T]{
 				path:      path,
 				typ:       nodeTypeParam,
 				paramName: path[1:],
@@ -222,11 +322,11 @@ func (n *node) childOrCreate(path string) *node {
 	}
 
 	if n.children == nil {
-		n.children = make(map[string]*node)
+		n.children = make(map[string]*routeNode[T])
 	}
 	child, ok := n.children[path]
 	if !ok {
-		child = &node{
+		child = &routeNode[T]{
 			path: path,
 			typ:  nodeTypeStatic,
 		}
@@ -240,7 +340,7 @@ func (n *node) childOrCreate(path string) *node {
 // 第二个返回值 bool 代表是否命中参数路由
 // 第三个返回值 bool 代表是否命中正则路由
 // 第四个返回值 bool 代表是否命中
-func (n *node) childOf(path string) (*node, bool, bool, bool) {
+func (n *routeNode[T]) childOf(path string) (*routeNode[T], bool, bool, bool) {
 	if path == "" { //需要对空字段支持正则路由命中么？
 		return nil, false, false, false
 	}
@@ -266,12 +366,15 @@ func (n *node) childOf(path string) (*node, bool, bool, bool) {
 	return res, false, false, ok
 }
 
-type matchInfo struct {
-	n          *node
+// matchInfo 是 HTTP 路由的匹配结果
+type matchInfo = routeMatch[HandleFunc]
+
+type routeMatch[T any] struct {
+	n          *routeNode[T]
 	pathParams map[string]string
 }
 
-func (m *matchInfo) addValue(key string, value string) {
+func (m *routeMatch[T]) addValue(key string, value string) {
 	if m.pathParams == nil {
 		// 大多数情况，参数路径只会有一段
 		m.pathParams = map[string]string{key: value}
@@ -279,15 +382,28 @@ func (m *matchInfo) addValue(key string, value string) {
 	m.pathParams[key] = value
 }
 
+// bindParamNames 按照命中节点上记录的参数名重新绑定参数值
+// 命中的节点没有注册路由，或者参数个数对不上的时候，保留按照节点名字绑定的结果
+func (m *routeMatch[T]) bindParamNames(values []string) {
+	names := m.n.paramNames
+	if len(names) == 0 || len(names) != len(values) {
+		return
+	}
+	m.pathParams = make(map[string]string, len(names))
+	for i, name := range names {
+		m.pathParams[name] = values[i]
+	}
+}
+
 func (r *router) PrintAllRouters() { //DFS
-	for method, root := range r.trees {
+	for method, tree := range r.trees {
 		fmt.Printf("======================\n")
 		fmt.Printf("打印路由树，树名为 %s:\n", method)
-		root.printNode("/")
+		tree.root.printNode("/")
 	}
 }
 
-func (n *node) printNode(concatPath string) (upLayerPath string) {
+func (n *routeNode[T]) printNode(concatPath string) (upLayerPath string) {
 	fmt.Printf("--------------\n")
 	fmt.Printf("路由节点名为：%s\n", n.path)
 	if n.path != "/" {
@@ -297,21 +413,21 @@ func (n *node) printNode(concatPath string) (upLayerPath string) {
 	case nodeTypeStatic:
 		fmt.Printf("路由节点类型为：%s\n", "静态路由节点")
 		fmt.Printf("路由地址：%s\n", concatPath)
-		fmt.Printf("句柄为 %s\n", runtime.FuncForPC(reflect.ValueOf(n.handler).Pointer()).Name())
+		fmt.Printf("句柄为 %s\n", handlerName(n.handler))
 	case nodeTypeReg:
 		fmt.Printf("路由节点类型为：%s\n", "正则路由节点")
 		fmt.Printf("路由地址：%s\n", concatPath)
-		fmt.Printf("句柄为 %s\n", runtime.FuncForPC(reflect.ValueOf(n.handler).Pointer()).Name())
+		fmt.Printf("句柄为 %s\n", handlerName(n.handler))
 		fmt.Printf("正则表达式为：%s\n", n.regExpr.String())
 	case nodeTypeParam:
 		fmt.Printf("路由节点类型为：%s\n", "参数路由节点")
 		fmt.Printf("路由地址：%s\n", concatPath)
-		fmt.Printf("句柄为 %s\n", runtime.FuncForPC(reflect.ValueOf(n.handler).Pointer()).Name())
+		fmt.Printf("句柄为 %s\n", handlerName(n.handler))
 		fmt.Printf("参数名为：%s\n", n.paramName)
 	case nodeTypeAny:
 		fmt.Printf("路由节点类型为：%s\n", "通配符路由节点")
 		fmt.Printf("路由地址：%s\n", concatPath)
-		fmt.Printf("句柄为 %s\n", runtime.FuncForPC(reflect.ValueOf(n.handler).Pointer()).Name())
+		fmt.Printf("句柄为 %s\n", handlerName(n.handler))
 	}
 
 	if concatPath == "/" {
@@ -337,6 +453,15 @@ func (n *node) printNode(concatPath string) (upLayerPath string) {
 	return strings.TrimSuffix(concatPath, "/"+n.path)
 }
 
+// handlerName 返回 handler 的名字，handler 不是方法的时候直接输出它的值
+func handlerName(handler any) string {
+	val := reflect.ValueOf(handler)
+	if val.Kind() != reflect.Func {
+		return fmt.Sprintf("%v", handler)
+	}
+	return runtime.FuncForPC(val.Pointer()).Name()
+}
+
 func (r *router) VerifyRouter(method string, testPath string, wantedRouteNode *node) (string, bool) {
 	if wantedRouteNode == nil {
 		panic("想测试的路由不能为空")
@@ -352,7 +477,7 @@ func (r *router) VerifyRouter(method string, testPath string, wantedRouteNode *n
 	return msg, verified
 }
 
-func (n *node) equal(y *node) (string, bool) {
+func (n *routeNode[T]) equal(y *routeNode[T]) (string, bool) {
 	if y == nil {
 		return "目标节点为 nil", false
 	}
@@ -0,0 +1,217 @@
+package web
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// frozenTree 是一棵冻结了的路由树，是 Router 冻结之后的只读版本
+// 所有的节点都放在一个数组里面，子节点用下标表示；
+// 只有静态段的路由，额外用完整路径做 key 放进 map 里面，一次查找就能命中
+type frozenTree[T any] struct {
+	// nodes 所有节点，下标 0 是根节点
+	nodes []frozenNode[T]
+	// edges 所有的静态子节点，同一个节点的静态子节点是连续的，并且按照 path 排好序
+	edges []frozenEdge
+	// statics 只有静态段的路由，去掉前后分隔符的完整路径 => 匹配结果
+	// 匹配结果是共享的，不能修改
+	statics map[string]*routeMatch[T]
+	sep     byte
+}
+
+type frozenEdge struct {
+	path string
+	idx  int32
+}
+
+type frozenNode[T any] struct {
+	n         *routeNode[T]
+	paramName string
+	regExpr   *regexp.Regexp
+
+	// 静态子节点在 edges 里面的范围 [edgeStart, edgeEnd)
+	edgeStart int32
+	edgeEnd   int32
+
+	// 下标，-1 代表没有
+	regChild   int32
+	paramChild int32
+	starChild  int32
+
+	// absorb 为 true 代表这是一个没有任何子节点的通配符节点，会匹配剩下的所有路径
+	absorb bool
+}
+
+// Freeze 冻结路由
+// 冻结之后路由树会被编译成只读的结构来加速查找，继续注册路由会 panic。
+// 一般在所有路由注册完毕，启动服务器之前调用
+func (r *router) Freeze() {
+	for _, tree := range r.trees {
+		tree.Freeze()
+	}
+	r.frozen = true
+}
+
+func newFrozenTree[T any](root *routeNode[T], sep byte) *frozenTree[T] {
+	t := &frozenTree[T]{
+		statics: map[string]*routeMatch[T]{},
+		sep:     sep,
+	}
+	t.compile(root, true)
+	return t
+}
+
+// compile 把 n 以及它的子节点放进 t 里面，返回 n 的下标
+// static 代表从根节点到 n 是否都是静态节点
+func (t *frozenTree[T]) compile(n *routeNode[T], static bool) int32 {
+	idx := int32(len(t.nodes))
+	t.nodes = append(t.nodes, frozenNode[T]{
+		n:          n,
+		paramName:  n.paramName,
+		regExpr:    n.regExpr,
+		regChild:   -1,
+		paramChild: -1,
+		starChild:  -1,
+		absorb: n.typ == nodeTypeAny && len(n.children) == 0 &&
+			n.regChild == nil && n.paramChild == nil && n.starChild == nil,
+	})
+	static = static && n.typ == nodeTypeStatic
+	if static && n.registered {
+		t.statics[strings.Trim(n.route, string(t.sep))] = &routeMatch[T]{n: n}
+	}
+
+	// 先占好静态子节点的位置，保证它们在 edges 里面是连续的
+	keys := make([]string, 0, len(n.children))
+	for k := range n.children {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	start := int32(len(t.edges))
+	for _, k := range keys {
+		t.edges = append(t.edges, frozenEdge{path: k})
+	}
+	t.nodes[idx].edgeStart = start
+	t.nodes[idx].edgeEnd = start + int32(len(keys))
+	for i, k := range keys {
+		t.edges[int(start)+i].idx = t.compile(n.children[k], static)
+	}
+
+	if n.regChild != nil {
+		t.nodes[idx].regChild = t.compile(n.regChild, false)
+	}
+	if n.paramChild != nil {
+		t.nodes[idx].paramChild = t.compile(n.paramChild, false)
+	}
+	if n.starChild != nil {
+		t.nodes[idx].starChild = t.compile(n.starChild, false)
+	}
+	return idx
+}
+
+// findPath 和 routeNode.findPath 的语义一样，path 前后不能有分隔符
+func (t *frozenTree[T]) findPath(path string) (*routeMatch[T], bool) {
+	if mi, ok := t.statics[path]; ok {
+		return mi, true
+	}
+	if path == "" {
+		return &routeMatch[T]{n: t.nodes[0].n}, true
+	}
+	return t.find(path, t.sep)
+}
+
+// find 按照 sep 一段段匹配 path，path 前后不能有 sep
+// 不切分 path，避免分配内存
+func (t *frozenTree[T]) find(path string, sep byte) (*routeMatch[T], bool) {
+	mi := &routeMatch[T]{}
+	var values []string
+	idx := int32(0)
+	for {
+		seg := path
+		end := strings.IndexByte(path, sep)
+		if end >= 0 {
+			seg = path[:end]
+			path = path[end+1:]
+		}
+		var matchParam bool
+		idx, matchParam = t.childOf(idx, seg)
+		if idx < 0 {
+			return nil, false
+		}
+		if matchParam {
+			mi.addValue(t.nodes[idx].paramName, seg)
+			values = append(values, seg)
+		} else if t.nodes[idx].absorb {
+			break
+		}
+		if end < 0 {
+			break
+		}
+	}
+	mi.n = t.nodes[idx].n
+	mi.bindParamNames(values)
+	return mi, true
+}
+
+// matchSegs 和 node.matchSegs 的语义一样
+func (t *frozenTree[T]) matchSegs(segs []string) (*routeMatch[T], bool) {
+	mi := &routeMatch[T]{}
+	var values []string
+	idx := int32(0)
+	for _, seg := range segs {
+		var matchParam bool
+		idx, matchParam = t.childOf(idx, seg)
+		if idx < 0 {
+			return nil, false
+		}
+		if matchParam {
+			mi.addValue(t.nodes[idx].paramName, seg)
+			values = append(values, seg)
+		} else if t.nodes[idx].absorb {
+			break
+		}
+	}
+	mi.n = t.nodes[idx].n
+	mi.bindParamNames(values)
+	return mi, true
+}
+
+// childOf 返回命中的子节点的下标，-1 代表没有命中
+// 第二个返回值代表是否命中了参数路由或者正则路由
+// 优先级和 node.childOf 一样：静态，正则，参数，通配符
+func (t *frozenTree[T]) childOf(idx int32, seg string) (int32, bool) {
+	if seg == "" {
+		return -1, false
+	}
+	n := &t.nodes[idx]
+	if child := t.staticChild(n, seg); child >= 0 {
+		return child, false
+	}
+	if n.regChild >= 0 && t.nodes[n.regChild].regExpr.FindString(seg) != "" {
+		return n.regChild, true
+	}
+	if n.paramChild >= 0 {
+		return n.paramChild, true
+	}
+	return n.starChild, false
+}
+
+func (t *frozenTree[T]) staticChild(n *frozenNode[T], seg string) int32 {
+	edges := t.edges[n.edgeStart:n.edgeEnd]
+	// 子节点少的时候，线性查找更快
+	if len(edges) <= 8 {
+		for _, e := range edges {
+			if e.path == seg {
+				return e.idx
+			}
+		}
+		return -1
+	}
+	i := sort.Search(len(edges), func(i int) bool {
+		return edges[i].path >= seg
+	})
+	if i < len(edges) && edges[i].path == seg {
+		return edges[i].idx
+	}
+	return -1
+}
@@ -0,0 +1,125 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+var frozenTestRoutes = []struct {
+	method string
+	path   string
+}{
+	{method: http.MethodGet, path: "/"},
+	{method: http.MethodGet, path: "/user"},
+	{method: http.MethodGet, path: "/user/home"},
+	{method: http.MethodGet, path: "/user/*/home"},
+	{method: http.MethodGet, path: "/param/:id"},
+	{method: http.MethodGet, path: "/param/:id/detail"},
+	{method: http.MethodGet, path: "/param/:name/profile"},
+	{method: http.MethodGet, path: "/param/:id/*"},
+	{method: http.MethodGet, path: "/items/a"},
+	{method: http.MethodGet, path: "/items/b"},
+	{method: http.MethodGet, path: "/items/c"},
+	{method: http.MethodGet, path: "/items/d"},
+	{method: http.MethodGet, path: "/items/e"},
+	{method: http.MethodGet, path: "/items/f"},
+	{method: http.MethodGet, path: "/items/g"},
+	{method: http.MethodGet, path: "/items/h"},
+	{method: http.MethodGet, path: "/items/i"},
+	{method: http.MethodGet, path: "/items/j"},
+	{method: http.MethodGet, path: "/items/:name"},
+	{method: http.MethodPost, path: "/order/create"},
+	{method: http.MethodPost, path: "/order/*"},
+	{method: http.MethodPost, path: "/*"},
+	{method: http.MethodDelete, path: "/reg/:id(.*)"},
+	{method: http.MethodDelete, path: "/:id([0-9]+)/home"},
+	{method: http.MethodDelete, path: "/:id([0-9]+)/home/:sub"},
+}
+
+var frozenTestPaths = []struct {
+	method string
+	path   string
+}{
+	{method: http.MethodHead, path: "/"},
+	{method: http.MethodGet, path: "/"},
+	{method: http.MethodGet, path: "/abc"},
+	{method: http.MethodGet, path: "/user"},
+	{method: http.MethodGet, path: "/user/"},
+	{method: http.MethodGet, path: "/user/home"},
+	{method: http.MethodGet, path: "/user//home"},
+	{method: http.MethodGet, path: "/user/tom/home"},
+	{method: http.MethodGet, path: "/user/tom"},
+	{method: http.MethodGet, path: "/param/123"},
+	{method: http.MethodGet, path: "/param/123/detail"},
+	{method: http.MethodGet, path: "/param/tom/profile"},
+	{method: http.MethodGet, path: "/param/123/abc/def"},
+	{method: http.MethodGet, path: "/items/a"},
+	{method: http.MethodGet, path: "/items/j"},
+	{method: http.MethodGet, path: "/items/k"},
+	{method: http.MethodGet, path: "/items/k/l"},
+	{method: http.MethodPost, path: "/order"},
+	{method: http.MethodPost, path: "/order/create"},
+	{method: http.MethodPost, path: "/order/delete/123/abc"},
+	{method: http.MethodPost, path: "/abc"},
+	{method: http.MethodDelete, path: "/reg/123"},
+	{method: http.MethodDelete, path: "/123/home"},
+	{method: http.MethodDelete, path: "/123/home/abc"},
+	{method: http.MethodDelete, path: "/abc/home"},
+}
+
+func Test_router_Freeze(t *testing.T) {
+	mockHandler := func(ctx *Context) {}
+	r := newRouter()
+	frozen := newRouter()
+	for _, tr := range frozenTestRoutes {
+		r.addRoute(tr.method, tr.path, mockHandler)
+		frozen.addRoute(tr.method, tr.path, mockHandler)
+	}
+	frozen.Freeze()
+	// 重复冻结没有影响
+	frozen.Freeze()
+
+	for _, tc := range frozenTestPaths {
+		t.Run(tc.method+" "+tc.path, func(t *testing.T) {
+			wantMi, wantFound := r.findRoute(tc.method, tc.path)
+			mi, found := frozen.findRoute(tc.method, tc.path)
+			assert.Equal(t, wantFound, found)
+			if !found {
+				return
+			}
+			assert.Equal(t, wantMi.pathParams, mi.pathParams)
+			assert.Equal(t, wantMi.n.route, mi.n.route)
+			assert.Equal(t, wantMi.n.path, mi.n.path)
+			assert.Equal(t, reflect.ValueOf(wantMi.n.handler), reflect.ValueOf(mi.n.handler))
+
+			wantMi, wantFound, _ = r.findEscapedRoute(tc.method, tc.path)
+			mi, found, _ = frozen.findEscapedRoute(tc.method, tc.path)
+			assert.Equal(t, wantFound, found)
+			if found {
+				assert.Equal(t, wantMi.pathParams, mi.pathParams)
+				assert.Equal(t, wantMi.n.route, mi.n.route)
+			}
+		})
+	}
+
+	assert.PanicsWithValue(t, "web: 路由已经冻结，不能继续注册路由 [/new]", func() {
+		frozen.addRoute(http.MethodGet, "/new", mockHandler)
+	})
+}
+
+func BenchmarkFindRouterFrozen(b *testing.B) {
+	mockHandler := func(ctx *Context) {}
+	r := newRouter()
+	for _, tr := range frozenTestRoutes {
+		r.addRoute(tr.method, tr.path, mockHandler)
+	}
+	r.Freeze()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, tc := range frozenTestPaths {
+			_, _ = r.findRoute(tc.method, tc.path)
+		}
+	}
+}
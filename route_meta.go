@@ -0,0 +1,128 @@
+package web
+
+import (
+	"sort"
+	"time"
+)
+
+// RouteMeta 是注册路由的时候附加的元数据
+// 框架会处理其中的 Timeout 和 BodyLimit：Timeout 决定请求 Context 的截止时间，
+// BodyLimit 限制绑定请求的时候读取的请求体大小。
+// 其它字段框架不解读，它们是给 middleware 和各种工具使用的
+type RouteMeta struct {
+	// Name 路由的名字
+	Name string
+	// Description 路由的描述
+	Description string
+	// Tags 路由的标签，例如用于分组展示
+	Tags []string
+	// Scopes 访问这个路由需要的权限
+	Scopes []string
+	// Timeout 处理请求的超时时间，0 代表不限制
+	// 超时之后 Context.Done 会被关闭，handler 需要自己检查，HTTPServer 不会中断 handler
+	Timeout time.Duration
+	// BodyLimit 请求体的大小限制，0 代表使用 HTTPServer 的配置
+	BodyLimit int64
+	// Extra 其它自定义的元数据
+	Extra map[string]any
+}
+
+// emptyRouteMeta 是没有命中路由的时候使用的元数据
+var emptyRouteMeta = &RouteMeta{}
+
+// Value 返回自定义的元数据
+func (m *RouteMeta) Value(key string) (any, bool) {
+	val, ok := m.Extra[key]
+	return val, ok
+}
+
+type RouteOption func(meta *RouteMeta)
+
+func RouteWithName(name string) RouteOption {
+	return func(meta *RouteMeta) {
+		meta.Name = name
+	}
+}
+
+func RouteWithDescription(desc string) RouteOption {
+	return func(meta *RouteMeta) {
+		meta.Description = desc
+	}
+}
+
+func RouteWithTags(tags ...string) RouteOption {
+	return func(meta *RouteMeta) {
+		meta.Tags = append(meta.Tags, tags...)
+	}
+}
+
+func RouteWithScopes(scopes ...string) RouteOption {
+	return func(meta *RouteMeta) {
+		meta.Scopes = append(meta.Scopes, scopes...)
+	}
+}
+
+func RouteWithTimeout(timeout time.Duration) RouteOption {
+	return func(meta *RouteMeta) {
+		meta.Timeout = timeout
+	}
+}
+
+func RouteWithBodyLimit(limit int64) RouteOption {
+	return func(meta *RouteMeta) {
+		meta.BodyLimit = limit
+	}
+}
+
+// RouteWithValue 设置自定义的元数据
+func RouteWithValue(key string, val any) RouteOption {
+	return func(meta *RouteMeta) {
+		if meta.Extra == nil {
+			meta.Extra = make(map[string]any, 1)
+		}
+		meta.Extra[key] = val
+	}
+}
+
+// RouteInfo 是一条已经注册的路由
+type RouteInfo struct {
+	// Method 是 HTTP 方法，通用的 Router 里面是空字符串
+	Method string
+	// Path 注册时候的路由，例如 /user/:id
+	Path string
+	Meta *RouteMeta
+}
+
+// Routes 返回所有注册了的路由，按照 HTTP 方法和路由排序
+func (r *router) Routes() []RouteInfo {
+	res := make([]RouteInfo, 0, 16)
+	for method, tree := range r.trees {
+		res = tree.root.appendRoutes(method, res)
+	}
+	sortRoutes(res)
+	return res
+}
+
+func sortRoutes(routes []RouteInfo) {
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Method != routes[j].Method {
+			return routes[i].Method < routes[j].Method
+		}
+		return routes[i].Path < routes[j].Path
+	})
+}
+
+func (n *routeNode[T]) appendRoutes(method string, res []RouteInfo) []RouteInfo {
+	if n.registered {
+		res = append(res, RouteInfo{Method: method, Path: n.route, Meta: n.meta})
+	}
+	for _, child := range n.children {
+		res = child.appendRoutes(method, res)
+	}
+	for _, child := range []*routeNode[T]{n.regChild, n.paramChild, n.starChild} {
+		if child != nil {
+			res = child.appendRoutes(method, res)
+		}
+	}
+	return res
+}
@@ -0,0 +1,87 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_router_Routes(t *testing.T) {
+	mockHandler := func(ctx *Context) {}
+	r := newRouter()
+	r.addRoute(http.MethodGet, "/user/:id", mockHandler,
+		RouteWithName("user-detail"),
+		RouteWithDescription("查询用户"),
+		RouteWithTags("user"),
+		RouteWithScopes("user:read"),
+		RouteWithTimeout(time.Second),
+		RouteWithBodyLimit(1024),
+		RouteWithValue("owner", "tom"))
+	r.addRoute(http.MethodGet, "/", mockHandler)
+	r.addRoute(http.MethodGet, "/user/:name/profile", mockHandler)
+	r.addRoute(http.MethodPost, "/order/*", mockHandler)
+
+	routes := r.Routes()
+	paths := make([]string, 0, len(routes))
+	for _, rt := range routes {
+		paths = append(paths, rt.Method+" "+rt.Path)
+	}
+	assert.Equal(t, []string{
+		"GET /",
+		"GET /user/:id",
+		"GET /user/:name/profile",
+		"POST /order/*",
+	}, paths)
+
+	meta := routes[1].Meta
+	assert.Equal(t, &RouteMeta{
+		Name:        "user-detail",
+		Description: "查询用户",
+		Tags:        []string{"user"},
+		Scopes:      []string{"user:read"},
+		Timeout:     time.Second,
+		BodyLimit:   1024,
+		Extra:       map[string]any{"owner": "tom"},
+	}, meta)
+	val, ok := meta.Value("owner")
+	assert.True(t, ok)
+	assert.Equal(t, "tom", val)
+	_, ok = routes[0].Meta.Value("owner")
+	assert.False(t, ok)
+}
+
+func TestHTTPServer_routeMeta(t *testing.T) {
+	s := NewHTTPServer()
+	var meta *RouteMeta
+	s.Get("/user/:id", func(ctx *Context) {
+		meta = ctx.RouteMeta
+	}, RouteWithName("user-detail"))
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/user/123", nil))
+	assert.Equal(t, "user-detail", meta.Name)
+}
+
+func TestHTTPServer_routeMetaNotFound(t *testing.T) {
+	var metas []*RouteMeta
+	s := NewHTTPServer(ServerWithMethodNotAllowed(JSONMethodNotAllowed),
+		ServerWithMiddleware(func(next HandleFunc) HandleFunc {
+			return func(ctx *Context) {
+				next(ctx)
+				_, ok := ctx.RouteMeta.Value("team")
+				assert.False(t, ok)
+				metas = append(metas, ctx.RouteMeta)
+			}
+		}))
+	s.Get("/user/:id", func(ctx *Context) {}, RouteWithValue("team", "user"))
+
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/order/123", nil))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/user/123", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+
+	assert.Equal(t, []*RouteMeta{{}, {}}, metas)
+}
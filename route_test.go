@@ -3,6 +3,7 @@ package web
 import (
 	"fmt"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"log"
 	"net/http"
 	"reflect"
@@ -90,7 +91,7 @@ func Test_router_AddRoute(t *testing.T) {
 		r.addRoute(tr.method, tr.path, mockHandler)
 	}
 
-	wantRouter := &router{
+	wantRouter := &nodeTrees{
 		trees: map[string]*node{
 			http.MethodGet: {
 				path: "/",
@@ -229,7 +230,7 @@ func Test_router_AddRoute(t *testing.T) {
 	log.Printf("%s", msg)
 	assert.True(t, ok, msg)
 
-	wantRouter.PrintAllRouters()
+	wantRouter.router().PrintAllRouters()
 
 	// 非法用例
 	r = newRouter()
@@ -308,20 +309,89 @@ func Test_router_AddRoute(t *testing.T) {
 		r.addRoute(http.MethodGet, "/a/b/:id(.*)", mockHandler)
 		r.addRoute(http.MethodGet, "/a/b/:id", mockHandler)
 	})
-	// 参数冲突
-	assert.PanicsWithValue(t, "web: 路由冲突，参数路由冲突，已有 :id，新注册 :name", func() {
+	// 同一个位置不同名字的参数路由，最终落在同一个节点上，属于路由冲突
+	assert.PanicsWithValue(t, "web: 路由冲突[/a/b/c/:name]", func() {
 		r.addRoute(http.MethodGet, "/a/b/c/:id", mockHandler)
 		r.addRoute(http.MethodGet, "/a/b/c/:name", mockHandler)
 	})
+	// 正则表达式不同
+	r = newRouter()
+	assert.PanicsWithValue(t, "web: 路由冲突，正则路由冲突，已有 :id([0-9]+)，新注册 :name([a-z]+)", func() {
+		r.addRoute(http.MethodGet, "/a/:id([0-9]+)", mockHandler)
+		r.addRoute(http.MethodGet, "/a/:name([a-z]+)/b", mockHandler)
+	})
+}
+
+func Test_router_differentParamNames(t *testing.T) {
+	mockHandler := func(ctx *Context) {}
+	r := newRouter()
+	r.addRoute(http.MethodGet, "/user/:id/posts", mockHandler)
+	r.addRoute(http.MethodGet, "/user/:name/profile", mockHandler)
+	r.addRoute(http.MethodGet, "/user/:name/profile/:section", mockHandler)
+	r.addRoute(http.MethodGet, "/order/:id([0-9]+)/detail", mockHandler)
+	r.addRoute(http.MethodGet, "/order/:oid([0-9]+)/items/*", mockHandler)
+
+	testCases := []struct {
+		name       string
+		path       string
+		pathParams map[string]string
+	}{
+		{
+			name:       "id",
+			path:       "/user/123/posts",
+			pathParams: map[string]string{"id": "123"},
+		},
+		{
+			name:       "name",
+			path:       "/user/tom/profile",
+			pathParams: map[string]string{"name": "tom"},
+		},
+		{
+			name:       "name and section",
+			path:       "/user/tom/profile/avatar",
+			pathParams: map[string]string{"name": "tom", "section": "avatar"},
+		},
+		{
+			name:       "reg id",
+			path:       "/order/12/detail",
+			pathParams: map[string]string{"id": "12"},
+		},
+		{
+			name:       "reg oid with star",
+			path:       "/order/12/items/a/b",
+			pathParams: map[string]string{"oid": "12"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mi, found := r.findRoute(http.MethodGet, tc.path)
+			assert.True(t, found)
+			assert.NotNil(t, mi.n.handler)
+			assert.Equal(t, tc.pathParams, mi.pathParams)
+		})
+	}
+}
+
+// nodeTrees 是期望的路由树，直接用节点来写比较直观
+type nodeTrees struct {
+	trees map[string]*node
+}
+
+func (r nodeTrees) router() *router {
+	res := newRouter()
+	for method, root := range r.trees {
+		res.trees[method] = &Router[HandleFunc]{sep: '/', root: root}
+	}
+	return &res
 }
 
-func (r router) equal(y router) (string, bool) {
+func (r nodeTrees) equal(y router) (string, bool) {
 	for k, v := range r.trees {
 		yv, ok := y.trees[k]
 		if !ok {
 			return fmt.Sprintf("目标 router 里面没有方法 %s 的路由树", k), false
 		}
-		str, ok := v.equal(yv)
+		str, ok := v.equal(yv.root)
 		if !ok {
 			return k + "-" + str, ok
 		}
@@ -682,3 +752,81 @@ func Test_router_findRoute(t *testing.T) {
 		})
 	}
 }
+
+func Test_router_findEscapedRoute(t *testing.T) {
+	mockHandler := func(ctx *Context) {}
+	r := newRouter()
+	r.addRoute(http.MethodGet, "/file/:name", mockHandler)
+	r.addRoute(http.MethodGet, "/file/:name/raw", mockHandler)
+
+	mi, found, err := r.findEscapedRoute(http.MethodGet, "/file/a%2Fb%20c/raw")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, map[string]string{"name": "a/b c"}, mi.pathParams)
+
+	// 非法的转义序列
+	_, found, err = r.findEscapedRoute(http.MethodGet, "/file/%zz")
+	assert.Error(t, err)
+	assert.False(t, found)
+
+	_, found, err = r.findEscapedRoute(http.MethodPost, "/file/abc")
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
+
+func Test_router_regexWithSlash(t *testing.T) {
+	// 和 Router 一样，正则表达式括号里面的 / 不会被切分
+	mockHandler := func(ctx *Context) {}
+	r := newRouter()
+	r.addRoute(http.MethodGet, "/file/:path(^[a-z]+/[a-z]+$)", mockHandler)
+	r.addRoute(http.MethodGet, "/file/:path(^[a-z]+/[a-z]+$)/raw", mockHandler)
+
+	generic := NewRouter[string]('/')
+	generic.Add("file/:path(^[a-z]+/[a-z]+$)", "file")
+	generic.Add("file/:path(^[a-z]+/[a-z]+$)/raw", "raw")
+
+	for _, frozen := range []bool{false, true} {
+		if frozen {
+			r.Freeze()
+			generic.Freeze()
+		}
+		mi, found, err := r.findEscapedRoute(http.MethodGet, "/file/a%2Fb/raw")
+		require.NoError(t, err)
+		require.True(t, found)
+		assert.Equal(t, "/file/:path(^[a-z]+/[a-z]+$)/raw", mi.n.route)
+		assert.Equal(t, map[string]string{"path": "a/b"}, mi.pathParams)
+
+		_, found = r.findRoute(http.MethodGet, "/file/a/b")
+		assert.False(t, found)
+		_, found = generic.Find("file/a/b")
+		assert.False(t, found)
+	}
+	assert.Equal(t, []RouteInfo{
+		{Path: "file/:path(^[a-z]+/[a-z]+$)", Meta: &RouteMeta{}},
+		{Path: "file/:path(^[a-z]+/[a-z]+$)/raw", Meta: &RouteMeta{}},
+	}, generic.Routes())
+}
+
+func Test_router_parentheses(t *testing.T) {
+	mockHandler := func(ctx *Context) {}
+	r := newRouter()
+	// 静态路由段里面的括号没有特殊含义，不会跨过 / 合并
+	r.addRoute(http.MethodGet, "/a(b/c", mockHandler)
+	r.addRoute(http.MethodGet, "/d)e/f", mockHandler)
+	for _, path := range []string{"/a(b/c", "/d)e/f"} {
+		mi, found := r.findRoute(http.MethodGet, path)
+		require.True(t, found, path)
+		assert.Equal(t, path, mi.n.route)
+	}
+
+	assert.PanicsWithValue(t, "web: 非法路由，正则表达式的括号不匹配 [/a/:id([0-9]+/b]", func() {
+		r.addRoute(http.MethodGet, "/a/:id([0-9]+/b", mockHandler)
+	})
+	assert.PanicsWithValue(t, "web: 非法路由，正则表达式的括号不匹配 [/a/:id)/b]", func() {
+		r.addRoute(http.MethodGet, "/a/:id)/b", mockHandler)
+	})
+	generic := NewRouter[string]('.')
+	assert.PanicsWithValue(t, "web: 非法路由，正则表达式的括号不匹配 [a.:id((.+)]", func() {
+		generic.Add("a.:id((.+)", "a")
+	})
+}
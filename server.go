@@ -1,6 +1,16 @@
 package web
 
-import "net/http"
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+)
 
 type HandleFunc func(ctx *Context)
 
@@ -13,7 +23,8 @@ type Server interface {
 
 	// addRoute 注册一个路由
 	// method 是 HTTP 方法
-	addRoute(method string, path string, handler HandleFunc)
+	// opts 是路由的元数据
+	addRoute(method string, path string, handler HandleFunc, opts ...RouteOption)
 	// 我们并不采取这种设计方案
 	// addRoute(method string, path string, handlers... HandleFunc)
 }
@@ -23,21 +34,137 @@ var _ Server = &HTTPServer{}
 
 type HTTPServer struct {
 	router
+
+	// useRawPath 为 true 的时候，按照转义之后的路径匹配路由
+	useRawPath bool
+
+	mdls []Middleware
+
+	// notFound 没有命中路由的时候执行
+	notFound HandleFunc
+	// methodNotAllowed 没有命中路由，但是其它 HTTP 方法能够命中的时候执行
+	// 为 nil 的时候和 notFound 一样处理
+	methodNotAllowed HandleFunc
+	// internalError 不为 nil 的时候，handler 里面的 panic 会被捕获，然后执行 internalError
+	internalError HandleFunc
+	// errorHandler 处理 HandleErrFunc 返回的 error，为 nil 的时候使用默认的处理逻辑
+	errorHandler ErrorHandler
+
+	// bodyLimit 绑定请求体时候的大小限制
+	bodyLimit int64
+	// strictBinding 为 true 的时候，不允许请求里面出现未知字段
+	strictBinding bool
+	// codecs 媒体类型 => Codec，绑定请求体和序列化响应的时候使用
+	codecs map[string]Codec
+	// tplEngine 模板引擎，Context.Render 使用
+	tplEngine TemplateEngine
+	// trustedProxies 可信的代理，Context.ClientIP 使用
+	trustedProxies []netip.Prefix
+	// clientIPHeader Context.ClientIP 使用的请求头
+	clientIPHeader string
+}
+
+type HTTPServerOption func(server *HTTPServer)
+
+func NewHTTPServer(opts ...HTTPServerOption) *HTTPServer {
+	res := &HTTPServer{
+		router:   newRouter(),
+		notFound: defaultNotFound,
+		codecs:   defaultCodecs(),
+	}
+	for _, opt := range opts {
+		opt(res)
+	}
+	return res
+}
+
+// ServerWithMiddleware 注册 middleware
+// 按照注册的顺序执行，第一个注册的在最外层
+func ServerWithMiddleware(mdls ...Middleware) HTTPServerOption {
+	return func(server *HTTPServer) {
+		server.mdls = append(server.mdls, mdls...)
+	}
 }
 
-func NewHTTPServer() *HTTPServer {
-	return &HTTPServer{
-		router: newRouter(),
+// ServerWithNotFound 设置没有命中路由时候的处理逻辑，默认返回 404 Not Found
+// 它和普通的 handler 一样会经过所有的 middleware。JSON 格式的响应可以使用 JSONNotFound
+func ServerWithNotFound(handler HandleFunc) HTTPServerOption {
+	return func(server *HTTPServer) {
+		server.notFound = handler
+	}
+}
+
+// ServerWithMethodNotAllowed 设置路径能够命中，但是 HTTP 方法不对时候的处理逻辑
+// 执行之前已经设置好了 Allow 响应头。JSON 格式的响应可以使用 JSONMethodNotAllowed
+// 没有设置的时候，和没有命中路由一样处理
+func ServerWithMethodNotAllowed(handler HandleFunc) HTTPServerOption {
+	return func(server *HTTPServer) {
+		server.methodNotAllowed = handler
+	}
+}
+
+// ServerWithInternalError 设置 handler 发生 panic 时候的处理逻辑
+// 设置之后 HTTPServer 会捕获 handler 里面的 panic，记录日志之后执行 handler，
+// 所以 middleware 依旧可以看到 500 响应。JSON 格式的响应可以使用 JSONInternalError
+// middleware 里面的 panic 需要使用 recovery 之类的 middleware 来处理
+func ServerWithInternalError(handler HandleFunc) HTTPServerOption {
+	return func(server *HTTPServer) {
+		server.internalError = handler
+	}
+}
+
+// ServerWithRawPath 使用 URL.EscapedPath() 来匹配路由
+// 路径只按照真正的 / 切分，例如 /file/a%2Fb 能够命中 /file/:name，并且 name = a/b。
+// 每一段路径参数都会被反转义。
+// 非法的转义序列在 net/http 解析请求的时候就已经返回 400 了，不会到达 HTTPServer
+func ServerWithRawPath() HTTPServerOption {
+	return func(server *HTTPServer) {
+		server.useRawPath = true
 	}
 }
 
 // ServeHTTP HTTPServer 处理请求的入口
 func (s *HTTPServer) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
 	ctx := &Context{
-		Req:  request,
-		Resp: writer,
+		Req:            request,
+		Resp:           WrapResponseWriter(writer),
+		bodyLimit:      s.bodyLimit,
+		strictBinding:  s.strictBinding,
+		codecs:         s.codecs,
+		tplEngine:      s.tplEngine,
+		trustedProxies: s.trustedProxies,
+		clientIPHeader: s.clientIPHeader,
 	}
-	s.serve(ctx)
+	// 最后一个应该是 HTTPServer 执行路由匹配，执行用户代码
+	root := s.serve
+	// 从后往前组装
+	for i := len(s.mdls) - 1; i >= 0; i-- {
+		root = s.mdls[i](root)
+	}
+	root(ctx)
+	// 所有的 middleware 都执行完了，才真正写入响应
+	s.flashResp(ctx)
+}
+
+// flashResp 把 Context 里面缓存的响应写回去
+// 响应头还没有发送的时候，按照 RespData 设置 Content-Length，
+// 所以 middleware 修改了 RespData 也不会导致 Content-Length 不对
+func (s *HTTPServer) flashResp(ctx *Context) {
+	resp := WrapResponseWriter(ctx.Resp)
+	if !resp.Written() && len(ctx.RespData) > 0 {
+		resp.Header().Set("Content-Length", strconv.Itoa(len(ctx.RespData)))
+	}
+	if ctx.RespStatusCode > 0 {
+		ctx.Resp.WriteHeader(ctx.RespStatusCode)
+	}
+	if len(ctx.RespData) > 0 {
+		_, _ = ctx.Resp.Write(ctx.RespData)
+	}
+}
+
+// Use 注册 middleware，参考 ServerWithMiddleware
+func (s *HTTPServer) Use(mdls ...Middleware) {
+	s.mdls = append(s.mdls, mdls...)
 }
 
 // Start 启动服务器
@@ -45,21 +172,108 @@ func (s *HTTPServer) Start(addr string) error {
 	return http.ListenAndServe(addr, s)
 }
 
-func (s *HTTPServer) Post(path string, handler HandleFunc) {
-	s.addRoute(http.MethodPost, path, handler)
+func (s *HTTPServer) Post(path string, handler HandleFunc, opts ...RouteOption) {
+	s.addRoute(http.MethodPost, path, handler, opts...)
 }
 
-func (s *HTTPServer) Get(path string, handler HandleFunc) {
-	s.addRoute(http.MethodGet, path, handler)
+func (s *HTTPServer) Get(path string, handler HandleFunc, opts ...RouteOption) {
+	s.addRoute(http.MethodGet, path, handler, opts...)
 }
 
 func (s *HTTPServer) serve(ctx *Context) {
-	mi, ok := s.findRoute(ctx.Req.Method, ctx.Req.URL.Path)
+	mi, ok := s.matchRoute(ctx.Req.Method, ctx.Req.URL)
 	if !ok || mi.n == nil || mi.n.handler == nil {
-		ctx.Resp.WriteHeader(404)
-		ctx.Resp.Write([]byte("Not Found"))
+		ctx.RouteMeta = emptyRouteMeta
+		if s.methodNotAllowed != nil {
+			if allowed := s.allowedMethods(ctx.Req.URL); len(allowed) > 0 {
+				ctx.Resp.Header().Set("Allow", strings.Join(allowed, ", "))
+				s.methodNotAllowed(ctx)
+				return
+			}
+		}
+		s.notFound(ctx)
 		return
 	}
 	ctx.PathParams = mi.pathParams
+	ctx.RouteMeta = mi.n.meta
+	ctx.MatchedRoute = mi.n.route
+	ctx.MatchedRouteType = RouteType(mi.n.typ)
+	if ctx.RouteMeta != nil && ctx.RouteMeta.Timeout > 0 {
+		cancel := ctx.WithTimeout(ctx.RouteMeta.Timeout)
+		defer cancel()
+	}
+	if s.internalError != nil {
+		defer func() {
+			if err := recover(); err != nil {
+				// 用户主动要求中断连接
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+				log.Printf("web: 处理请求 %s %s 发生 panic，路由 %s: %v\n%s",
+					ctx.Req.Method, ctx.Req.URL.Path, ctx.MatchedRoute, err, debug.Stack())
+				// 响应已经发送了一部分，没有办法再返回 500，也不能把错误追加到已经写入的响应后面
+				if WrapResponseWriter(ctx.Resp).Written() {
+					ctx.RespStatusCode = 0
+					ctx.RespData = nil
+					return
+				}
+				s.internalError(ctx)
+			}
+		}()
+	}
 	mi.n.handler(ctx)
 }
+
+// matchRoute 按照 HTTPServer 的配置查找路由
+func (s *HTTPServer) matchRoute(method string, u *url.URL) (*matchInfo, bool) {
+	if s.useRawPath {
+		// EscapedPath 总是返回合法的转义，所以这里不会有 error
+		mi, ok, err := s.findEscapedRoute(method, u.EscapedPath())
+		return mi, ok && err == nil
+	}
+	return s.findRoute(method, u.Path)
+}
+
+// allowedMethods 返回能够处理 u 的 HTTP 方法，按照字母序排序
+func (s *HTTPServer) allowedMethods(u *url.URL) []string {
+	var res []string
+	for method := range s.trees {
+		mi, ok := s.matchRoute(method, u)
+		if ok && mi.n.handler != nil {
+			res = append(res, method)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+func defaultNotFound(ctx *Context) {
+	ctx.RespStatusCode = http.StatusNotFound
+	ctx.RespData = []byte(http.StatusText(http.StatusNotFound))
+}
+
+// JSONNotFound 以 JSON 的形式返回 404
+func JSONNotFound(ctx *Context) {
+	jsonError(ctx, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+}
+
+// JSONMethodNotAllowed 以 JSON 的形式返回 405
+func JSONMethodNotAllowed(ctx *Context) {
+	jsonError(ctx, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+}
+
+// JSONInternalError 以 JSON 的形式返回 500
+func JSONInternalError(ctx *Context) {
+	jsonError(ctx, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+}
+
+// jsonError 返回 {"code": 404, "message": "Not Found"} 这种形式的响应
+func jsonError(ctx *Context, code int, message string) {
+	ctx.Resp.Header().Set("Content-Type", "application/json; charset=utf-8")
+	ctx.RespStatusCode = code
+	// 只有 int 和 string，不会出错
+	ctx.RespData, _ = json.Marshal(struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}{Code: code, Message: message})
+}
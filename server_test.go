@@ -1,7 +1,9 @@
 package web
 
 import (
+	"github.com/stretchr/testify/assert"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -329,3 +331,276 @@ func BenchmarkFindRouter(b *testing.B) {
 		}
 	}
 }
+
+func TestHTTPServer_rawPath(t *testing.T) {
+	testCases := []struct {
+		name       string
+		opts       []HTTPServerOption
+		path       string
+		wantCode   int
+		wantParams map[string]string
+	}{
+		{
+			name:       "encoded slash",
+			opts:       []HTTPServerOption{ServerWithRawPath()},
+			path:       "/file/a%2Fb",
+			wantCode:   http.StatusOK,
+			wantParams: map[string]string{"name": "a/b"},
+		},
+		{
+			name:       "encoded space",
+			opts:       []HTTPServerOption{ServerWithRawPath()},
+			path:       "/file/a%20b",
+			wantCode:   http.StatusOK,
+			wantParams: map[string]string{"name": "a b"},
+		},
+		{
+			name:       "encoded static segment",
+			opts:       []HTTPServerOption{ServerWithRawPath()},
+			path:       "/%66ile/abc",
+			wantCode:   http.StatusOK,
+			wantParams: map[string]string{"name": "abc"},
+		},
+		{
+			// 默认按照 URL.Path 匹配，%2F 会被当成分隔符
+			name:     "default encoded slash",
+			path:     "/file/a%2Fb",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:       "default encoded space",
+			path:       "/file/a%20b",
+			wantCode:   http.StatusOK,
+			wantParams: map[string]string{"name": "a b"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewHTTPServer(tc.opts...)
+			var params map[string]string
+			s.Get("/file/:name", func(ctx *Context) {
+				params = ctx.PathParams
+			})
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantParams, params)
+		})
+	}
+
+	// RawPath 非法的时候，EscapedPath 会按照 Path 重新转义
+	s := NewHTTPServer(ServerWithRawPath())
+	var params map[string]string
+	s.Get("/file/:name", func(ctx *Context) {
+		params = ctx.PathParams
+	})
+	req := httptest.NewRequest(http.MethodGet, "/file/a%20b", nil)
+	req.URL.RawPath = "/file/%zz"
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, map[string]string{"name": "a b"}, params)
+}
+
+func TestHTTPServer_matchedRoute(t *testing.T) {
+	s := NewHTTPServer()
+	mockHandler := func(ctx *Context) {
+		ctx.Resp.Write([]byte(ctx.MatchedRoute + " " + ctx.MatchedRouteType.String()))
+	}
+	s.Get("/", mockHandler)
+	s.Get("/user/:id", mockHandler)
+	s.Get("/user/:name/profile", mockHandler)
+	s.Get("/order/:id([0-9]+)", mockHandler)
+	s.Get("/static/*", mockHandler)
+
+	testCases := []struct {
+		path     string
+		wantBody string
+	}{
+		{path: "/", wantBody: "/ static"},
+		{path: "/user/123", wantBody: "/user/:id param"},
+		{path: "/user/tom/profile", wantBody: "/user/:name/profile static"},
+		{path: "/order/12", wantBody: "/order/:id([0-9]+) regexp"},
+		{path: "/static/js/app.js", wantBody: "/static/* any"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+		})
+	}
+}
+
+func TestHTTPServer_middleware(t *testing.T) {
+	var logs []string
+	mdl := func(name string) Middleware {
+		return func(next HandleFunc) HandleFunc {
+			return func(ctx *Context) {
+				logs = append(logs, name+" before")
+				next(ctx)
+				logs = append(logs, name+" after")
+			}
+		}
+	}
+	s := NewHTTPServer(ServerWithMiddleware(mdl("first")))
+	s.Use(mdl("second"))
+	s.Get("/", func(ctx *Context) {
+		logs = append(logs, "handler")
+	})
+	s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, []string{"first before", "second before", "handler", "second after", "first after"}, logs)
+}
+
+func TestHTTPServer_flashResp(t *testing.T) {
+	// 把 404 替换成自定义的错误页面
+	errPage := func(next HandleFunc) HandleFunc {
+		return func(ctx *Context) {
+			next(ctx)
+			if ctx.RespStatusCode == http.StatusNotFound {
+				ctx.Resp.Header().Set("Content-Type", "text/html")
+				ctx.RespData = []byte("<h1>页面不存在</h1>")
+			}
+		}
+	}
+	s := NewHTTPServer(ServerWithMiddleware(errPage))
+	s.Get("/user", func(ctx *Context) {
+		ctx.RespStatusCode = http.StatusCreated
+		ctx.RespData = []byte("hello")
+	})
+	s.Get("/missing", func(ctx *Context) {
+		ctx.RespStatusCode = http.StatusNotFound
+		ctx.RespData = []byte("not found")
+	})
+	s.Get("/direct", func(ctx *Context) {
+		_, _ = ctx.Resp.Write([]byte("direct"))
+	})
+
+	testCases := []struct {
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{path: "/user", wantCode: http.StatusCreated, wantBody: "hello"},
+		{path: "/missing", wantCode: http.StatusNotFound, wantBody: "<h1>页面不存在</h1>"},
+		{path: "/unknown", wantCode: http.StatusNotFound, wantBody: "<h1>页面不存在</h1>"},
+		{path: "/direct", wantCode: http.StatusOK, wantBody: "direct"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+		})
+	}
+}
+
+func TestHTTPServer_errorHandlers(t *testing.T) {
+	var visited []string
+	mdl := func(next HandleFunc) HandleFunc {
+		return func(ctx *Context) {
+			next(ctx)
+			visited = append(visited, ctx.Req.URL.Path)
+		}
+	}
+	testCases := []struct {
+		name      string
+		opts      []HTTPServerOption
+		method    string
+		path      string
+		wantCode  int
+		wantBody  string
+		wantAllow string
+	}{
+		{
+			name:     "default not found",
+			method:   http.MethodGet,
+			path:     "/abc",
+			wantCode: http.StatusNotFound,
+			wantBody: "Not Found",
+		},
+		{
+			name:     "method not allowed without handler",
+			method:   http.MethodPut,
+			path:     "/user/123",
+			wantCode: http.StatusNotFound,
+			wantBody: "Not Found",
+		},
+		{
+			name: "custom not found",
+			opts: []HTTPServerOption{ServerWithNotFound(func(ctx *Context) {
+				ctx.RespStatusCode = http.StatusNotFound
+				ctx.RespData = []byte("找不到")
+			})},
+			method:   http.MethodGet,
+			path:     "/abc",
+			wantCode: http.StatusNotFound,
+			wantBody: "找不到",
+		},
+		{
+			name:     "json not found",
+			opts:     []HTTPServerOption{ServerWithNotFound(JSONNotFound)},
+			method:   http.MethodGet,
+			path:     "/abc",
+			wantCode: http.StatusNotFound,
+			wantBody: `{"code":404,"message":"Not Found"}`,
+		},
+		{
+			name:      "json method not allowed",
+			opts:      []HTTPServerOption{ServerWithMethodNotAllowed(JSONMethodNotAllowed)},
+			method:    http.MethodPut,
+			path:      "/user/123",
+			wantCode:  http.StatusMethodNotAllowed,
+			wantBody:  `{"code":405,"message":"Method Not Allowed"}`,
+			wantAllow: "GET, POST",
+		},
+		{
+			name:     "json method not allowed but path not found",
+			opts:     []HTTPServerOption{ServerWithMethodNotAllowed(JSONMethodNotAllowed)},
+			method:   http.MethodPut,
+			path:     "/abc",
+			wantCode: http.StatusNotFound,
+			wantBody: "Not Found",
+		},
+		{
+			name:     "json internal error",
+			opts:     []HTTPServerOption{ServerWithInternalError(JSONInternalError)},
+			method:   http.MethodGet,
+			path:     "/panic",
+			wantCode: http.StatusInternalServerError,
+			wantBody: `{"code":500,"message":"Internal Server Error"}`,
+		},
+		{
+			// 已经写入了一部分响应，不会再追加错误信息
+			name:     "internal error after partial write",
+			opts:     []HTTPServerOption{ServerWithInternalError(JSONInternalError)},
+			method:   http.MethodGet,
+			path:     "/partial",
+			wantCode: http.StatusOK,
+			wantBody: "partial",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			visited = nil
+			s := NewHTTPServer(append(tc.opts, ServerWithMiddleware(mdl))...)
+			s.Get("/user/:id", func(ctx *Context) {})
+			s.Post("/user/:id", func(ctx *Context) {})
+			s.Get("/panic", func(ctx *Context) {
+				panic("boom")
+			})
+			s.Get("/partial", func(ctx *Context) {
+				_, _ = ctx.Resp.Write([]byte("partial"))
+				panic("boom")
+			})
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, httptest.NewRequest(tc.method, tc.path, nil))
+			assert.Equal(t, tc.wantCode, recorder.Code)
+			assert.Equal(t, tc.wantBody, recorder.Body.String())
+			assert.Equal(t, tc.wantAllow, recorder.Header().Get("Allow"))
+			// 经过了 middleware
+			assert.Equal(t, []string{tc.path}, visited)
+		})
+	}
+}
@@ -0,0 +1,298 @@
+package web
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TemplateEngine 模板引擎，通过 ServerWithTemplateEngine 设置之后，可以使用 Context.Render 渲染页面
+type TemplateEngine interface {
+	// Render 渲染名字为 name 的模板
+	Render(name string, data any) ([]byte, error)
+}
+
+// ServerWithTemplateEngine 设置模板引擎
+func ServerWithTemplateEngine(engine TemplateEngine) HTTPServerOption {
+	return func(server *HTTPServer) {
+		server.tplEngine = engine
+	}
+}
+
+// Render 使用模板引擎渲染名字为 name 的模板，作为 200 的 HTML 响应
+// 没有设置模板引擎或者渲染失败的时候返回 error，并且不会修改响应
+func (c *Context) Render(name string, data any) error {
+	if c.tplEngine == nil {
+		return errors.New("web: 没有设置模板引擎")
+	}
+	page, err := c.tplEngine.Render(name, data)
+	if err != nil {
+		return err
+	}
+	return c.HTML(http.StatusOK, string(page))
+}
+
+// GoTemplateEngine 基于 html/template 的 TemplateEngine
+// 模板的名字是它在 fs.FS 里面的路径，例如 pages/index.html，引用其它模板的时候也使用这个名字。
+// 每一个页面都有自己独立的模板集合，里面包含了所有的共享模板，也就是布局和局部模板，
+// 所以不同的页面可以用 define 定义同名的 block。
+// 设置了布局之后，渲染页面实际执行的是布局，页面通过 define 覆盖布局里面的 block。
+// 默认只在创建的时候解析一次模板，开发的时候可以使用 GoTemplateWithReload 在文件变化之后重新解析
+type GoTemplateEngine struct {
+	fsys fs.FS
+	// pages 页面的 glob 模式
+	pages []string
+	// shared 布局和局部模板的 glob 模式
+	shared []string
+	// layout 布局的名字，为空的时候直接执行页面
+	layout string
+	funcs  template.FuncMap
+
+	// tpls 页面名字 => 页面的模板集合
+	// 共享模板也可以直接渲染，它们使用同一个只有共享模板的集合
+	tpls map[string]*template.Template
+
+	// reloadInterval 大于 0 的时候是开发模式，渲染的时候最多每隔 reloadInterval 检查一次文件有没有变化
+	reloadInterval time.Duration
+	// mutex 保护开发模式下的 tpls，checkedAt 和 stamps
+	mutex     sync.Mutex
+	checkedAt time.Time
+	// stamps 上一次解析的时候，所有模板文件的修改时间和大小
+	stamps map[string]fileStamp
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+type GoTemplateOption func(engine *GoTemplateEngine)
+
+// NewGoTemplateEngine 从 fsys 加载 pages 匹配的页面
+// pages 是 fs.Glob 的模式，例如 pages/*.html，不能为空，并且每一个模式都至少要匹配一个文件
+func NewGoTemplateEngine(fsys fs.FS, pages []string, opts ...GoTemplateOption) (*GoTemplateEngine, error) {
+	if len(pages) == 0 {
+		return nil, errors.New("web: 页面模板不能为空")
+	}
+	res := &GoTemplateEngine{
+		fsys:  fsys,
+		pages: pages,
+	}
+	for _, opt := range opts {
+		opt(res)
+	}
+	if res.reloadInterval > 0 {
+		stamps, err := res.stat()
+		if err != nil {
+			return nil, err
+		}
+		res.stamps = stamps
+		res.checkedAt = time.Now()
+	}
+	tpls, err := res.load(true)
+	if err != nil {
+		return nil, err
+	}
+	res.tpls = tpls
+	return res, nil
+}
+
+// GoTemplateWithShared 设置布局和局部模板的 glob 模式，例如 layouts/*.html，partials/*.html
+func GoTemplateWithShared(patterns ...string) GoTemplateOption {
+	return func(engine *GoTemplateEngine) {
+		engine.shared = append(engine.shared, patterns...)
+	}
+}
+
+// GoTemplateWithLayout 设置布局，name 是布局的名字，例如 layouts/base.html
+// 布局必须是共享模板
+func GoTemplateWithLayout(name string) GoTemplateOption {
+	return func(engine *GoTemplateEngine) {
+		engine.layout = name
+	}
+}
+
+// GoTemplateWithReload 开发模式，渲染的时候最多每隔 interval 检查一次模板文件，
+// 文件新增，删除或者修改了之后重新解析所有的模板，不需要重启服务器。
+// 检查依赖文件的修改时间和大小，所以 fs.FS 需要返回正确的 fs.FileInfo，例如 os.DirFS。
+// 重新解析失败的时候，每一次 Render 都会重新检查并且返回 error，直到解析成功。
+// 生产环境不要使用，每一次检查都要读取所有模板文件的信息
+func GoTemplateWithReload(interval time.Duration) GoTemplateOption {
+	return func(engine *GoTemplateEngine) {
+		engine.reloadInterval = interval
+	}
+}
+
+// GoTemplateWithFuncs 设置模板里面可以使用的函数
+func GoTemplateWithFuncs(funcs template.FuncMap) GoTemplateOption {
+	return func(engine *GoTemplateEngine) {
+		engine.funcs = funcs
+	}
+}
+
+func (e *GoTemplateEngine) Render(name string, data any) ([]byte, error) {
+	tpls, err := e.templates()
+	if err != nil {
+		return nil, err
+	}
+	tpl, ok := tpls[name]
+	if !ok {
+		return nil, fmt.Errorf("web: 模板 %s 不存在", name)
+	}
+	entry := name
+	// 页面模板集合的名字就是页面的名字，共享模板的集合的名字是空字符串
+	if e.layout != "" && tpl.Name() == name {
+		entry = e.layout
+	}
+	buf := &bytes.Buffer{}
+	if err := tpl.ExecuteTemplate(buf, entry, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// templates 返回当前的模板，开发模式下文件有变化的时候会重新解析
+func (e *GoTemplateEngine) templates() (map[string]*template.Template, error) {
+	if e.reloadInterval <= 0 {
+		return e.tpls, nil
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	now := time.Now()
+	if now.Sub(e.checkedAt) < e.reloadInterval {
+		return e.tpls, nil
+	}
+	stamps, err := e.stat()
+	if err != nil {
+		return nil, err
+	}
+	if sameStamps(stamps, e.stamps) {
+		e.checkedAt = now
+		return e.tpls, nil
+	}
+	// 重新加载失败的时候不更新 checkedAt，之后的每一次渲染都会重试并且返回 error，
+	// 避免在间隔时间内悄悄使用旧的模板
+	tpls, err := e.load(false)
+	if err != nil {
+		return nil, err
+	}
+	e.tpls = tpls
+	e.stamps = stamps
+	e.checkedAt = now
+	return tpls, nil
+}
+
+// stat 返回所有模板文件的修改时间和大小
+func (e *GoTemplateEngine) stat() (map[string]fileStamp, error) {
+	files, err := e.glob(append(append([]string{}, e.shared...), e.pages...), false)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]fileStamp, len(files))
+	for _, file := range files {
+		info, err := fs.Stat(e.fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		res[file] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+	}
+	return res, nil
+}
+
+func sameStamps(a, b map[string]fileStamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for file, sa := range a {
+		sb, ok := b[file]
+		if !ok || !sa.modTime.Equal(sb.modTime) || sa.size != sb.size {
+			return false
+		}
+	}
+	return true
+}
+
+// load 解析所有的模板
+// mustMatch 为 true 的时候，每一个模式都至少要匹配一个文件。
+// 开发模式下重新解析的时候为 false，删除了某个模式匹配的所有文件之后，其它模板依旧可以渲染
+func (e *GoTemplateEngine) load(mustMatch bool) (map[string]*template.Template, error) {
+	sharedFiles, err := e.glob(e.shared, mustMatch)
+	if err != nil {
+		return nil, err
+	}
+	pageFiles, err := e.glob(e.pages, mustMatch)
+	if err != nil {
+		return nil, err
+	}
+	shared := template.New("").Funcs(e.funcs)
+	for _, file := range sharedFiles {
+		if err = e.parse(shared, file); err != nil {
+			return nil, err
+		}
+	}
+	if e.layout != "" && shared.Lookup(e.layout) == nil {
+		return nil, fmt.Errorf("web: 布局 %s 不是共享模板", e.layout)
+	}
+	tpls := make(map[string]*template.Template, len(sharedFiles)+len(pageFiles))
+	for _, file := range sharedFiles {
+		tpls[file] = shared
+	}
+	for _, file := range pageFiles {
+		if _, ok := tpls[file]; ok {
+			return nil, fmt.Errorf("web: 模板 %s 既是页面又是共享模板", file)
+		}
+		tpl, err := shared.Clone()
+		if err != nil {
+			return nil, err
+		}
+		// 页面模板集合的名字就是页面的名字
+		tpl = tpl.New(file)
+		if err = e.parse(tpl, file); err != nil {
+			return nil, err
+		}
+		tpls[file] = tpl
+	}
+	return tpls, nil
+}
+
+// parse 把文件 name 解析成 tpl 里面名字为 name 的模板
+func (e *GoTemplateEngine) parse(tpl *template.Template, name string) error {
+	content, err := fs.ReadFile(e.fsys, name)
+	if err != nil {
+		return err
+	}
+	if tpl.Name() != name {
+		tpl = tpl.New(name)
+	}
+	_, err = tpl.Parse(string(content))
+	return err
+}
+
+// glob 返回 patterns 匹配的所有文件，去重并且排好序
+func (e *GoTemplateEngine) glob(patterns []string, mustMatch bool) ([]string, error) {
+	set := make(map[string]struct{})
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(e.fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 && mustMatch {
+			return nil, fmt.Errorf("web: 模板 %s 没有匹配任何文件", pattern)
+		}
+		for _, m := range matches {
+			set[m] = struct{}{}
+		}
+	}
+	res := make([]string, 0, len(set))
+	for m := range set {
+		res = append(res, m)
+	}
+	sort.Strings(res)
+	return res, nil
+}
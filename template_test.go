@@ -0,0 +1,271 @@
+package web
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func testTemplateFS() fstest.MapFS {
+	return fstest.MapFS{
+		"layouts/base.html": {Data: []byte(
+			`<title>{{block "title" .}}默认标题{{end}}</title>{{template "partials/nav.html" .}}` +
+				`<main>{{block "content" .}}{{end}}</main>`)},
+		"partials/nav.html": {Data: []byte(`<nav>{{upper .User}}</nav>`)},
+		"pages/index.html":  {Data: []byte(`{{define "content"}}首页 {{.User}}{{end}}`)},
+		"pages/user.html": {Data: []byte(
+			`{{define "title"}}用户{{end}}{{define "content"}}<p>{{.User}}</p>{{end}}`)},
+	}
+}
+
+func TestGoTemplateEngine_Render(t *testing.T) {
+	engine, err := NewGoTemplateEngine(testTemplateFS(), []string{"pages/*.html"},
+		GoTemplateWithShared("layouts/*.html", "partials/*.html"),
+		GoTemplateWithLayout("layouts/base.html"),
+		GoTemplateWithFuncs(template.FuncMap{"upper": strings.ToUpper}))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		tplName  string
+		data     any
+		wantErr  error
+		wantPage string
+	}{
+		{
+			name:     "index",
+			tplName:  "pages/index.html",
+			data:     map[string]string{"User": "tom"},
+			wantPage: `<title>默认标题</title><nav>TOM</nav><main>首页 tom</main>`,
+		},
+		{
+			// 不同的页面可以定义同名的 block，并且会转义
+			name:     "user",
+			tplName:  "pages/user.html",
+			data:     map[string]string{"User": "<b>jerry</b>"},
+			wantPage: `<title>用户</title><nav>&lt;B&gt;JERRY&lt;/B&gt;</nav><main><p>&lt;b&gt;jerry&lt;/b&gt;</p></main>`,
+		},
+		{
+			name:     "partial",
+			tplName:  "partials/nav.html",
+			data:     map[string]string{"User": "tom"},
+			wantPage: `<nav>TOM</nav>`,
+		},
+		{
+			name:    "not found",
+			tplName: "pages/abc.html",
+			wantErr: errors.New("web: 模板 pages/abc.html 不存在"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			page, err := engine.Render(tc.tplName, tc.data)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.wantPage, string(page))
+		})
+	}
+}
+
+func TestNewGoTemplateEngine(t *testing.T) {
+	testCases := []struct {
+		name    string
+		fsys    fstest.MapFS
+		pages   []string
+		opts    []GoTemplateOption
+		wantErr string
+	}{
+		{
+			name:    "no pages",
+			wantErr: "web: 页面模板不能为空",
+		},
+		{
+			name:    "no match",
+			pages:   []string{"views/*.html"},
+			wantErr: "web: 模板 views/*.html 没有匹配任何文件",
+		},
+		{
+			name:    "bad pattern",
+			pages:   []string{"pages/[.html"},
+			wantErr: "syntax error in pattern",
+		},
+		{
+			name:    "layout not shared",
+			pages:   []string{"pages/*.html"},
+			opts:    []GoTemplateOption{GoTemplateWithLayout("layouts/base.html")},
+			wantErr: "web: 布局 layouts/base.html 不是共享模板",
+		},
+		{
+			name:    "both page and shared",
+			pages:   []string{"pages/*.html"},
+			opts:    []GoTemplateOption{GoTemplateWithShared("pages/index.html")},
+			wantErr: "web: 模板 pages/index.html 既是页面又是共享模板",
+		},
+		{
+			name:    "undefined func",
+			pages:   []string{"pages/*.html"},
+			opts:    []GoTemplateOption{GoTemplateWithShared("partials/*.html")},
+			wantErr: `function "upper" not defined`,
+		},
+		{
+			name: "syntax error",
+			fsys: fstest.MapFS{
+				"pages/index.html": {Data: []byte(`{{if}}`)},
+			},
+			pages:   []string{"pages/*.html"},
+			wantErr: "missing value for if",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fsys := tc.fsys
+			if fsys == nil {
+				fsys = testTemplateFS()
+			}
+			_, err := NewGoTemplateEngine(fsys, tc.pages, tc.opts...)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
+
+func TestContext_Render_template(t *testing.T) {
+	engine, err := NewGoTemplateEngine(testTemplateFS(), []string{"pages/*.html"},
+		GoTemplateWithShared("layouts/*.html", "partials/*.html"),
+		GoTemplateWithLayout("layouts/base.html"),
+		GoTemplateWithFuncs(template.FuncMap{"upper": strings.ToUpper}))
+	require.NoError(t, err)
+	s := NewHTTPServer(ServerWithTemplateEngine(engine))
+	s.GetErr("/", func(ctx *Context) error {
+		return ctx.Render("pages/index.html", map[string]string{"User": "tom"})
+	})
+	s.GetErr("/missing", func(ctx *Context) error {
+		return ctx.Render("pages/missing.html", nil)
+	})
+
+	req, err := http.NewRequest(http.MethodGet, "/", nil)
+	require.NoError(t, err)
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "text/html; charset=utf-8", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, `<title>默认标题</title><nav>TOM</nav><main>首页 tom</main>`, recorder.Body.String())
+
+	req, err = http.NewRequest(http.MethodGet, "/missing", nil)
+	require.NoError(t, err)
+	recorder = httptest.NewRecorder()
+	s.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+
+	ctx := &Context{}
+	assert.Equal(t, errors.New("web: 没有设置模板引擎"), ctx.Render("pages/index.html", nil))
+}
+
+func TestGoTemplateWithReload(t *testing.T) {
+	start := time.Now()
+	fsys := fstest.MapFS{
+		"pages/index.html": {Data: []byte(`v1 {{.}}`), ModTime: start},
+	}
+	dev, err := NewGoTemplateEngine(fsys, []string{"pages/*.html"}, GoTemplateWithReload(time.Nanosecond))
+	require.NoError(t, err)
+	prod, err := NewGoTemplateEngine(fsys, []string{"pages/*.html"})
+	require.NoError(t, err)
+
+	render := func(engine *GoTemplateEngine, name string) string {
+		page, err := engine.Render(name, "tom")
+		require.NoError(t, err)
+		return string(page)
+	}
+	assert.Equal(t, "v1 tom", render(dev, "pages/index.html"))
+
+	// 修改文件
+	fsys["pages/index.html"] = &fstest.MapFile{Data: []byte(`v2 {{.}}`), ModTime: start.Add(time.Second)}
+	assert.Equal(t, "v2 tom", render(dev, "pages/index.html"))
+	assert.Equal(t, "v1 tom", render(prod, "pages/index.html"))
+
+	// 新增文件
+	fsys["pages/user.html"] = &fstest.MapFile{Data: []byte(`user {{.}}`), ModTime: start}
+	assert.Equal(t, "user tom", render(dev, "pages/user.html"))
+	_, err = prod.Render("pages/user.html", "tom")
+	assert.Error(t, err)
+
+	// 解析失败，修复之后恢复正常
+	fsys["pages/user.html"] = &fstest.MapFile{Data: []byte(`{{if}}`), ModTime: start.Add(time.Second)}
+	_, err = dev.Render("pages/user.html", "tom")
+	assert.Error(t, err)
+	fsys["pages/user.html"] = &fstest.MapFile{Data: []byte(`fixed {{.}}`), ModTime: start.Add(2 * time.Second)}
+	assert.Equal(t, "fixed tom", render(dev, "pages/user.html"))
+
+	// 删除文件
+	delete(fsys, "pages/user.html")
+	_, err = dev.Render("pages/user.html", "tom")
+	assert.Equal(t, errors.New("web: 模板 pages/user.html 不存在"), err)
+}
+
+func TestGoTemplateWithReload_interval(t *testing.T) {
+	start := time.Now()
+	fsys := fstest.MapFS{
+		"pages/index.html": {Data: []byte(`v1`), ModTime: start},
+	}
+	engine, err := NewGoTemplateEngine(fsys, []string{"pages/*.html"}, GoTemplateWithReload(time.Hour))
+	require.NoError(t, err)
+	fsys["pages/index.html"] = &fstest.MapFile{Data: []byte(`v2`), ModTime: start.Add(time.Second)}
+	// 还没有到检查的时间
+	page, err := engine.Render("pages/index.html", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", string(page))
+}
+
+func TestGoTemplateWithReload_brokenInInterval(t *testing.T) {
+	start := time.Now()
+	fsys := fstest.MapFS{
+		"pages/index.html": {Data: []byte(`v1`), ModTime: start},
+	}
+	engine, err := NewGoTemplateEngine(fsys, []string{"pages/*.html"}, GoTemplateWithReload(time.Hour))
+	require.NoError(t, err)
+
+	// 到了检查的时间，但是模板解析失败，间隔时间内的每一次渲染都要返回 error
+	engine.checkedAt = time.Time{}
+	fsys["pages/index.html"] = &fstest.MapFile{Data: []byte(`{{if}}`), ModTime: start.Add(time.Second)}
+	for i := 0; i < 3; i++ {
+		_, err = engine.Render("pages/index.html", nil)
+		assert.Error(t, err)
+	}
+
+	// 修复之后马上恢复，不需要等到下一次检查的时间
+	fsys["pages/index.html"] = &fstest.MapFile{Data: []byte(`v2`), ModTime: start.Add(2 * time.Second)}
+	page, err := engine.Render("pages/index.html", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "v2", string(page))
+
+	// 重新加载成功之后，间隔时间内不再检查
+	fsys["pages/index.html"] = &fstest.MapFile{Data: []byte(`v3`), ModTime: start.Add(3 * time.Second)}
+	page, err = engine.Render("pages/index.html", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "v2", string(page))
+}
+
+func TestGoTemplateWithReload_deleteAll(t *testing.T) {
+	start := time.Now()
+	fsys := fstest.MapFS{
+		"pages/index.html":   {Data: []byte(`index`), ModTime: start},
+		"emails/signup.html": {Data: []byte(`signup`), ModTime: start},
+	}
+	engine, err := NewGoTemplateEngine(fsys, []string{"pages/*.html", "emails/*.html"},
+		GoTemplateWithReload(time.Nanosecond))
+	require.NoError(t, err)
+
+	// 删除了某个模式匹配的所有文件，其它模板依旧可以渲染
+	delete(fsys, "emails/signup.html")
+	page, err := engine.Render("pages/index.html", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "index", string(page))
+	_, err = engine.Render("emails/signup.html", nil)
+	assert.Equal(t, errors.New("web: 模板 emails/signup.html 不存在"), err)
+}
@@ -0,0 +1,282 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// FieldError 是一个字段没有通过校验的原因
+type FieldError struct {
+	// Field 字段的路径，优先使用 json 标签里面的名字，例如 address.city，tags[0]
+	Field string `json:"field"`
+	// Rule 没有通过的规则，例如 required，min
+	Rule string `json:"rule"`
+	// Param 规则的参数，例如 min=1 里面的 1
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + " " + e.Message
+}
+
+// ValidationErrors 是所有没有通过校验的字段
+type ValidationErrors []*FieldError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, fe := range e {
+		msgs = append(msgs, fe.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validate 按照 validate 标签校验 val，val 必须是结构体或者指向结构体的指针
+// 支持的规则，多个规则之间用逗号分隔：
+//   - required 不能是零值，切片和 map 不能为空
+//   - min=n，max=n 字符串的长度，数字的大小，切片和 map 的长度
+//   - email 必须是合法的邮箱地址
+//   - oneof=a b 必须是空格分隔的值之一
+//
+// 结构体字段，以及切片里面的结构体会被递归校验。值为 nil 的指针，如果没有 required 规则就会被跳过。
+// 校验失败的时候返回 ValidationErrors，标签写错了会返回其它 error
+func Validate(val any) error {
+	v := reflect.ValueOf(val)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return fmt.Errorf("web: 不能校验 nil")
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("web: 只能校验结构体，实际是 %T", val)
+	}
+	var errs ValidationErrors
+	if err := validateStruct(v, "", &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Validate 校验 val，参考 Validate
+func (c *Context) Validate(val any) error {
+	return Validate(val)
+}
+
+// BindAndValidate 绑定请求之后校验，参考 Bind 和 Validate
+func (c *Context) BindAndValidate(val any) error {
+	if err := c.Bind(val); err != nil {
+		return err
+	}
+	return c.Validate(val)
+}
+
+// ValidationFailed 以 JSON 的形式返回 422
+// {"code":422,"message":"Unprocessable Entity","errors":[{"field":"name","rule":"required","message":"不能为空"}]}
+func (c *Context) ValidationFailed(errs ValidationErrors) {
+	c.Resp.Header().Set("Content-Type", "application/json; charset=utf-8")
+	c.RespStatusCode = http.StatusUnprocessableEntity
+	// FieldError 里面只有字符串，不会出错
+	c.RespData, _ = json.Marshal(struct {
+		Code    int              `json:"code"`
+		Message string           `json:"message"`
+		Errors  ValidationErrors `json:"errors"`
+	}{
+		Code:    http.StatusUnprocessableEntity,
+		Message: http.StatusText(http.StatusUnprocessableEntity),
+		Errors:  errs,
+	})
+}
+
+func validateStruct(v reflect.Value, prefix string, errs *ValidationErrors) error {
+	typ := v.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		fd := typ.Field(i)
+		if !fd.IsExported() && !fd.Anonymous {
+			continue
+		}
+		fv := v.Field(i)
+		path := prefix
+		// 嵌入的结构体，字段和自己的字段在同一层
+		if !fd.Anonymous {
+			path = joinFieldPath(prefix, jsonFieldName(fd))
+		}
+		if err := validateField(fv, path, fd.Tag.Get("validate"), errs); err != nil {
+			if _, ok := err.(*ruleError); ok {
+				return err
+			}
+			return &ruleError{field: strings.TrimPrefix(typ.Name()+"."+fd.Name, "."), err: err}
+		}
+	}
+	return nil
+}
+
+func validateField(fv reflect.Value, path string, tag string, errs *ValidationErrors) error {
+	if tag == "-" {
+		return nil
+	}
+	var rules []string
+	if tag != "" {
+		rules = strings.Split(tag, ",")
+	}
+	for _, rule := range rules {
+		name, param, _ := strings.Cut(rule, "=")
+		fe, err := checkRule(fv, name, param)
+		if err != nil {
+			return err
+		}
+		if fe != nil {
+			fe.Field = path
+			*errs = append(*errs, fe)
+			// 一个字段只报告第一个没有通过的规则
+			return nil
+		}
+	}
+	return validateNested(fv, path, errs)
+}
+
+// ruleError 是校验规则写错了的 error
+type ruleError struct {
+	field string
+	err   error
+}
+
+func (e *ruleError) Error() string {
+	return fmt.Sprintf("web: 字段 %s 的校验规则有误: %v", e.field, e.err)
+}
+
+func (e *ruleError) Unwrap() error {
+	return e.err
+}
+
+// validateNested 递归校验结构体，以及切片里面的元素
+func validateNested(fv reflect.Value, path string, errs *ValidationErrors) error {
+	for fv.Kind() == reflect.Pointer {
+		if fv.IsNil() {
+			return nil
+		}
+		fv = fv.Elem()
+	}
+	switch fv.Kind() {
+	case reflect.Struct:
+		return validateStruct(fv, path, errs)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < fv.Len(); i++ {
+			if err := validateNested(fv.Index(i), fmt.Sprintf("%s[%d]", path, i), errs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkRule 检查一条规则，没有通过的时候返回 FieldError
+func checkRule(fv reflect.Value, name string, param string) (*FieldError, error) {
+	if name == "required" {
+		if isEmptyValue(fv) {
+			return &FieldError{Rule: name, Message: "不能为空"}, nil
+		}
+		return nil, nil
+	}
+	// 其它的规则都不校验 nil 指针
+	for fv.Kind() == reflect.Pointer {
+		if fv.IsNil() {
+			return nil, nil
+		}
+		fv = fv.Elem()
+	}
+	switch name {
+	case "min", "max":
+		limit, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s 的参数 %q 不是数字", name, param)
+		}
+		size, isLen, err := measure(fv)
+		if err != nil {
+			return nil, err
+		}
+		if (name == "min" && size >= limit) || (name == "max" && size <= limit) {
+			return nil, nil
+		}
+		msg := "不能大于 "
+		if name == "min" {
+			msg = "不能小于 "
+		}
+		if isLen {
+			msg = "长度" + msg
+		}
+		return &FieldError{Rule: name, Param: param, Message: msg + param}, nil
+	case "email":
+		if fv.Kind() != reflect.String {
+			return nil, fmt.Errorf("email 只能用于字符串，实际是 %s", fv.Type())
+		}
+		addr, err := mail.ParseAddress(fv.String())
+		if err != nil || addr.Address != fv.String() {
+			return &FieldError{Rule: name, Message: "不是合法的邮箱地址"}, nil
+		}
+		return nil, nil
+	case "oneof":
+		str := fmt.Sprint(fv.Interface())
+		for _, opt := range strings.Fields(param) {
+			if opt == str {
+				return nil, nil
+			}
+		}
+		return &FieldError{Rule: name, Param: param, Message: "必须是 [" + param + "] 中的一个"}, nil
+	default:
+		return nil, fmt.Errorf("未知的规则 %s", name)
+	}
+}
+
+// measure 返回用于比较 min 和 max 的值
+// 字符串，切片和 map 返回长度，第二个返回值为 true；数字返回它自己
+func measure(fv reflect.Value) (float64, bool, error) {
+	switch fv.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(fv.String())), true, nil
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(fv.Len()), true, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(fv.Int()), false, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(fv.Uint()), false, nil
+	case reflect.Float32, reflect.Float64:
+		return fv.Float(), false, nil
+	default:
+		return 0, false, fmt.Errorf("min 和 max 不能用于 %s", fv.Type())
+	}
+}
+
+func isEmptyValue(fv reflect.Value) bool {
+	switch fv.Kind() {
+	case reflect.Slice, reflect.Map:
+		return fv.Len() == 0
+	default:
+		return fv.IsZero()
+	}
+}
+
+// jsonFieldName 返回字段在 json 标签里面的名字，没有的时候使用字段名
+func jsonFieldName(fd reflect.StructField) string {
+	name, _, _ := strings.Cut(fd.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return fd.Name
+	}
+	return name
+}
+
+func joinFieldPath(prefix string, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
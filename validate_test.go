@@ -0,0 +1,147 @@
+package web
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type validateAddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+type validateUser struct {
+	Name      string             `json:"name" validate:"required,min=1,max=4"`
+	Email     string             `json:"email" validate:"email"`
+	Role      string             `json:"role" validate:"oneof=admin member"`
+	Age       int                `json:"age" validate:"min=0,max=150"`
+	Tags      []string           `json:"tags" validate:"max=2"`
+	Address   validateAddress    `json:"address"`
+	Backup    *validateAddress   `json:"backup"`
+	Others    []validateAddress  `json:"others"`
+	Nickname  *string            `validate:"min=2"`
+	Extra     map[string]string  `json:"-" validate:"required"`
+	Ignored   string             `validate:"-"`
+	Addresses []*validateAddress `json:"addresses"`
+}
+
+func TestValidate(t *testing.T) {
+	short := "a"
+	testCases := []struct {
+		name    string
+		val     any
+		wantErr ValidationErrors
+	}{
+		{
+			name: "valid",
+			val: &validateUser{
+				Name:    "Tom",
+				Email:   "tom@example.com",
+				Role:    "admin",
+				Age:     18,
+				Tags:    []string{"a"},
+				Address: validateAddress{City: "Shanghai"},
+				Others:  []validateAddress{{City: "Beijing"}},
+				Extra:   map[string]string{"a": "b"},
+			},
+		},
+		{
+			name: "invalid",
+			val: validateUser{
+				Name:      "Jerry",
+				Email:     "Jerry <jerry@example.com>",
+				Role:      "guest",
+				Age:       -1,
+				Tags:      []string{"a", "b", "c"},
+				Backup:    &validateAddress{},
+				Others:    []validateAddress{{City: "Beijing"}, {}},
+				Nickname:  &short,
+				Addresses: []*validateAddress{nil, {}},
+			},
+			wantErr: ValidationErrors{
+				{Field: "name", Rule: "max", Param: "4", Message: "长度不能大于 4"},
+				{Field: "email", Rule: "email", Message: "不是合法的邮箱地址"},
+				{Field: "role", Rule: "oneof", Param: "admin member", Message: "必须是 [admin member] 中的一个"},
+				{Field: "age", Rule: "min", Param: "0", Message: "不能小于 0"},
+				{Field: "tags", Rule: "max", Param: "2", Message: "长度不能大于 2"},
+				{Field: "address.city", Rule: "required", Message: "不能为空"},
+				{Field: "backup.city", Rule: "required", Message: "不能为空"},
+				{Field: "others[1].city", Rule: "required", Message: "不能为空"},
+				{Field: "Nickname", Rule: "min", Param: "2", Message: "长度不能小于 2"},
+				{Field: "Extra", Rule: "required", Message: "不能为空"},
+				{Field: "addresses[1].city", Rule: "required", Message: "不能为空"},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Validate(tc.val)
+			if tc.wantErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Equal(t, tc.wantErr, err)
+		})
+	}
+}
+
+func TestValidate_invalidInput(t *testing.T) {
+	assert.Error(t, Validate(123))
+	assert.Error(t, Validate((*validateUser)(nil)))
+	assert.EqualError(t, Validate(struct {
+		Name string `validate:"unknown"`
+	}{}), "web: 字段 Name 的校验规则有误: 未知的规则 unknown")
+	assert.Error(t, Validate(struct {
+		Age int `validate:"min=abc"`
+	}{}))
+	// 嵌套结构体里面的规则有误，报告最里层的字段
+	assert.EqualError(t, Validate(struct {
+		Address struct {
+			City int `validate:"email"`
+		}
+	}{}), "web: 字段 City 的校验规则有误: email 只能用于字符串，实际是 int")
+}
+
+func TestContext_BindAndValidate(t *testing.T) {
+	type user struct {
+		Name  string `json:"name" validate:"required"`
+		Email string `json:"email" validate:"email"`
+	}
+	testCases := []struct {
+		name     string
+		opts     []HTTPServerOption
+		wantBody string
+	}{
+		{
+			name: "default",
+			wantBody: `{"code":422,"message":"Unprocessable Entity","errors":[` +
+				`{"field":"name","rule":"required","message":"不能为空"},` +
+				`{"field":"email","rule":"email","message":"不是合法的邮箱地址"}]}`,
+		},
+		{
+			name: "problem",
+			opts: []HTTPServerOption{ServerWithProblemDetails()},
+			wantBody: `{"type":"about:blank","title":"Unprocessable Entity","status":422,` +
+				`"detail":"请求参数没有通过校验","instance":"/user","route":"/user","errors":[` +
+				`{"field":"name","rule":"required","message":"不能为空"},` +
+				`{"field":"email","rule":"email","message":"不是合法的邮箱地址"}]}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewHTTPServer(tc.opts...)
+			s.PostErr("/user", func(ctx *Context) error {
+				var u user
+				return ctx.BindAndValidate(&u)
+			})
+			req := httptest.NewRequest(http.MethodPost, "/user", strings.NewReader(`{"email":"abc"}`))
+			req.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			s.ServeHTTP(recorder, req)
+			assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			assert.JSONEq(t, tc.wantBody, recorder.Body.String())
+		})
+	}
+}